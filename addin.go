@@ -0,0 +1,155 @@
+package main
+
+import (
+    "regexp"
+    "sort"
+)
+
+const (
+    relTags            = relOfficeDoc + "tags"
+    relWebExtTaskpanes = "http://schemas.microsoft.com/office/2011/relationships/webextensiontaskpanes"
+    relWebExtension    = "http://schemas.microsoft.com/office/2011/relationships/webextension"
+)
+
+var (
+    emptyCustDataRe = regexp.MustCompile(`<p:custDataLst\s*(?:/>|>\s*</p:custDataLst>)`)
+    tagEntryRe      = regexp.MustCompile(`<p:tag\b[^>]*>`)
+    webExtRefRe     = regexp.MustCompile(`<we:reference\b[^>]*>`)
+    webExtPropRe    = regexp.MustCompile(`<we:property\b[^>]*>`)
+    webExtAnchorRe  = regexp.MustCompile(`<\w+:webextensionref\b[^>]*>`)
+    // 内容加载项所在的图形框及其外层锚点、兼容性分支
+    webExtFrameNames = []string{"xdr:graphicFrame", "p:graphicFrame", "xdr:twoCellAnchor", "xdr:oneCellAnchor", "xdr:absoluteAnchor", "mc:AlternateContent"}
+)
+
+// removeTags 删除 PowerPoint 加载项写入的客户数据标签(ppt/tags)及其引用
+func removeTags(dir string) error {
+    var targets []string
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        var ids []string
+        for _, r := range readRels(dir, rels) {
+            if r.Type == relTags && !r.external() {
+                ids = append(ids, r.ID)
+                targets = append(targets, resolveTarget(source, r.Target))
+            }
+        }
+        if len(ids) == 0 {
+            continue
+        }
+        data, err := readPart(dir, source)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        for _, id := range ids {
+            text = regexp.MustCompile(`<p:tags\s+r:id="`+regexp.QuoteMeta(id)+`"\s*/>`).ReplaceAllString(text, "")
+        }
+        text = emptyCustDataRe.ReplaceAllString(text, "")
+        if err := writePart(dir, source, []byte(text)); err != nil {
+            return err
+        }
+    }
+
+    for _, t := range targets {
+        if !partExists(dir, t) {
+            continue
+        }
+        logPrintf("删除标签部件: %s", t)
+        if err := removePartTree(dir, t); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// removeWebExtAnchors 删除引用 Web 扩展的图形框，连同其所在的绘图锚点或兼容性分支，
+// 同时返回被删除内容中引用的关系 ID
+func removeWebExtAnchors(text string, ids map[string]bool) (string, []string) {
+    var spans [][2]int
+    for _, m := range webExtAnchorRe.FindAllStringIndex(text, -1) {
+        if !ids[xmlAttr(text[m[0]:m[1]], "r:id")] {
+            continue
+        }
+        span := [2]int{m[0], m[1]}
+        for _, name := range webExtFrameNames {
+            if s, ok := enclosingSpan(text, name, span[0], span[1]); ok {
+                span = s
+            }
+        }
+        spans = append(spans, span)
+    }
+    sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+    var refs []string
+    for _, s := range spans {
+        for _, m := range relIDRe.FindAllStringSubmatch(text[s[0]:s[1]], -1) {
+            refs = append(refs, m[1])
+        }
+    }
+    return removeSpans(text, spans), refs
+}
+
+// removeWebExtensions 删除任务窗格加载项、绘图和幻灯片中的内容加载项，
+// 以及只被它们引用的 Web 扩展部件
+func removeWebExtensions(dir string) error {
+    for _, t := range partsByRelType(dir, relWebExtTaskpanes) {
+        logPrintf("删除任务窗格加载项: %s", t)
+        if err := removePartTree(dir, t); err != nil {
+            return err
+        }
+    }
+
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        ids := map[string]bool{}
+        for _, r := range readRels(dir, rels) {
+            if r.Type == relWebExtension && !r.external() {
+                ids[r.ID] = true
+            }
+        }
+        if len(ids) == 0 {
+            continue
+        }
+        data, err := readPart(dir, source)
+        if err != nil {
+            continue
+        }
+        text, refs := removeWebExtAnchors(string(data), ids)
+        if len(refs) == 0 {
+            continue
+        }
+        logPrintf("删除内容加载项: %s", source)
+        if err := writePart(dir, source, []byte(text)); err != nil {
+            return err
+        }
+        if err := removeDroppedRels(dir, source, text, refs); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectAddins(dir string) {
+    for _, t := range partsByRelType(dir, relTags) {
+        data, err := readPart(dir, t)
+        if err != nil {
+            continue
+        }
+        var names []string
+        for _, tag := range tagEntryRe.FindAllString(string(data), -1) {
+            names = append(names, xmlUnescape(xmlAttr(tag, "name")))
+        }
+        reportPrintf("  标签: %s %v", t, names)
+    }
+
+    for _, t := range partsByRelType(dir, relWebExtension) {
+        data, err := readPart(dir, t)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        ref := webExtRefRe.FindString(text)
+        reportPrintf("  Web 扩展: %s %s (%s) 属性 %d 个", t, xmlAttr(ref, "id"), xmlAttr(ref, "store"),
+            len(webExtPropRe.FindAllString(text, -1)))
+    }
+}
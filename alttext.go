@@ -0,0 +1,98 @@
+package main
+
+import (
+    "regexp"
+    "strings"
+)
+
+var (
+    nvPrTagRe     = regexp.MustCompile(`<(?:\w+:cNvPr|wp:docPr)\b[^>]*>`)
+    vmlTitleTagRe = regexp.MustCompile(`<v:(?:imagedata|shape|rect|oval|roundrect)\b[^>]*>`)
+    // 盘符路径、UNC 路径、绝对路径、file: 地址，或以图片扩展名结尾的文件名
+    fileNameRe = regexp.MustCompile(`(?i)(?:^[a-z]:[\\/]|^\\\\[^\\]|^/[^/\s]|^file:|[^\s\\/.]\.(?:jpe?g|png|gif|bmp|tiff?|emf|wmf|svg|heic|webp|ico)\s*$)`)
+)
+
+// looksLikeFileName 判断属性值是否像文件名或路径(插入图片时 Office 自动写入的原始文件名)
+func looksLikeFileName(v string) bool {
+    return fileNameRe.MatchString(xmlUnescape(v))
+}
+
+// cleanAltText 清理图片名称、替代文字和 VML 标题中的原始文件名
+// files: 只处理像文件名或路径的值；all: 替代文字和标题一律清空
+func cleanAltText(dir, policy string) error {
+    for _, p := range listParts(dir) {
+        if !strings.HasSuffix(p, ".xml") && !strings.HasSuffix(p, ".vml") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        count := 0
+        text = nvPrTagRe.ReplaceAllStringFunc(text, func(tag string) string {
+            orig := tag
+            if looksLikeFileName(xmlAttr(tag, "name")) {
+                tag = setXMLAttr(tag, "name", "Picture "+xmlAttr(tag, "id"))
+            }
+            for _, a := range []string{"descr", "title"} {
+                if v := xmlAttr(tag, a); v != "" && (policy == "all" || looksLikeFileName(v)) {
+                    tag = removeXMLAttr(tag, a)
+                }
+            }
+            if tag != orig {
+                count++
+            }
+            return tag
+        })
+        text = vmlTitleTagRe.ReplaceAllStringFunc(text, func(tag string) string {
+            orig := tag
+            for _, a := range []string{"o:title", "alt"} {
+                if v := xmlAttr(tag, a); v != "" && (policy == "all" || looksLikeFileName(v)) {
+                    tag = removeXMLAttr(tag, a)
+                }
+            }
+            if tag != orig {
+                count++
+            }
+            return tag
+        })
+        if count == 0 {
+            continue
+        }
+        logPrintf("清理图片名称和替代文字: %s 共 %d 处", p, count)
+        if err := writePart(dir, p, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectAltText(dir string) {
+    for _, p := range listParts(dir) {
+        if !strings.HasSuffix(p, ".xml") && !strings.HasSuffix(p, ".vml") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        seen := map[string]bool{}
+        check := func(tag string, attrs ...string) {
+            for _, a := range attrs {
+                v := xmlAttr(tag, a)
+                if v != "" && looksLikeFileName(v) && !seen[v] {
+                    seen[v] = true
+                    reportPrintf("  图片文件名: %s %s=%s", p, a, xmlUnescape(v))
+                }
+            }
+        }
+        for _, tag := range nvPrTagRe.FindAllString(text, -1) {
+            check(tag, "name", "descr", "title")
+        }
+        for _, tag := range vmlTitleTagRe.FindAllString(text, -1) {
+            check(tag, "o:title", "alt")
+        }
+    }
+}
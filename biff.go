@@ -0,0 +1,275 @@
+package main
+
+import (
+    "encoding/binary"
+    "strings"
+)
+
+// BIFF8 记录类型(MS-XLS)
+const (
+    biffNote        = 0x001C
+    biffFilePass    = 0x002F
+    biffFileSharing = 0x005B
+    biffWriteAccess = 0x005C
+    biffBoundSheet  = 0x0085
+    biffExtSST      = 0x00FF
+    biffSupBook     = 0x01AE
+    biffIndex       = 0x020B
+)
+
+type biffRecord struct {
+    typ  uint16
+    pos  int
+    body []byte
+}
+
+// biffRecords 切分工作簿流中的记录，body 直接指向 data
+func biffRecords(data []byte) []biffRecord {
+    var list []biffRecord
+    for pos := 0; pos+4 <= len(data); {
+        typ := binary.LittleEndian.Uint16(data[pos:])
+        size := int(binary.LittleEndian.Uint16(data[pos+2:]))
+        if pos+4+size > len(data) {
+            break
+        }
+        list = append(list, biffRecord{typ, pos, data[pos+4 : pos+4+size]})
+        pos += 4 + size
+    }
+    return list
+}
+
+// xlStringSpan 返回 b[off:] 处 XLUnicodeString 的字符区间和每字符字节数
+func xlStringSpan(b []byte, off int) (start, end, width int, ok bool) {
+    if off+3 > len(b) {
+        return 0, 0, 0, false
+    }
+    cch := int(binary.LittleEndian.Uint16(b[off:]))
+    width = 1 + int(b[off+2]&1)
+    start = off + 3
+    end = start + cch*width
+    return start, end, width, end <= len(b)
+}
+
+func xlString(b []byte, off int) string {
+    start, end, width, ok := xlStringSpan(b, off)
+    if !ok {
+        return ""
+    }
+    if width == 2 {
+        return decodeUTF16LE(b[start:end])
+    }
+    return string(b[start:end])
+}
+
+// shortenXLString 返回把 b[off:] 处字符串替换为单个空格后的新内容
+// (批注作者、共享保护用户名要求至少 1 个字符)
+func shortenXLString(b []byte, off int) ([]byte, bool) {
+    _, end, _, ok := xlStringSpan(b, off)
+    if !ok {
+        return nil, false
+    }
+    out := append([]byte(nil), b[:off]...)
+    out = append(out, 1, 0, 0, ' ')
+    return append(out, b[end:]...), true
+}
+
+// rebuildBiff 按 bodies 替换记录内容重新生成工作簿流，
+// 并修正工作表位置(BoundSheet8)、DefColWidth 与 DBCELL 位置(INDEX)、共享字符串索引(EXTSST)中的绝对偏移
+func rebuildBiff(data []byte, records []biffRecord, bodies map[int][]byte) []byte {
+    type shift struct{ end, delta int }
+    var out []byte
+    var shifts []shift
+    tail := 0
+    for _, r := range records {
+        tail = r.pos + 4 + len(r.body)
+        body, ok := bodies[r.pos]
+        if !ok {
+            out = append(out, data[r.pos:tail]...)
+            continue
+        }
+        out = binary.LittleEndian.AppendUint16(out, r.typ)
+        out = binary.LittleEndian.AppendUint16(out, uint16(len(body)))
+        out = append(out, body...)
+        shifts = append(shifts, shift{tail, len(r.body) - len(body)})
+    }
+    out = append(out, data[tail:]...)
+
+    move := func(b []byte) {
+        old := int(binary.LittleEndian.Uint32(b))
+        d := 0
+        for _, s := range shifts {
+            if old >= s.end {
+                d += s.delta
+            }
+        }
+        binary.LittleEndian.PutUint32(b, uint32(old-d))
+    }
+    for _, r := range biffRecords(out) {
+        switch r.typ {
+        case biffBoundSheet:
+            if len(r.body) >= 4 {
+                move(r.body)
+            }
+        case biffIndex:
+            // ibXF(DefColWidth 位置)和 rgibRw(各 DBCELL 位置)
+            for i := 12; i+4 <= len(r.body); i += 4 {
+                move(r.body[i:])
+            }
+        case biffExtSST:
+            for i := 2; i+8 <= len(r.body); i += 8 {
+                move(r.body[i:])
+            }
+        }
+    }
+    return out
+}
+
+// supBookPathRange 返回外部工作簿路径中目录部分的字符区间，自引用和加载项返回 false
+func supBookPathRange(body []byte) (start, dirEnd, width int, ok bool) {
+    if len(body) < 4 {
+        return 0, 0, 0, false
+    }
+    cch := int(binary.LittleEndian.Uint16(body[2:]))
+    if cch == 0x0401 || cch == 0x3A01 || cch == 0 || len(body) < 5 {
+        return 0, 0, 0, false
+    }
+    width = 1 + int(body[4]&1)
+    start = 5
+    end := start + cch*width
+    if end > len(body) {
+        return 0, 0, 0, false
+    }
+    dirEnd = start
+    for i := start; i < end; i += width {
+        switch c := body[i]; {
+        case width == 2 && body[i+1] != 0:
+        case c == 0x01 && i+width < end:
+            // 卷标记后紧跟盘符
+            dirEnd = i + 2*width
+            i += width
+        case c == 0x02 || c == 0x03 || c == 0x04 || c == 0x05 || c == '\\' || c == '/':
+            dirEnd = i + width
+        }
+    }
+    return start, dirEnd, width, dirEnd > start
+}
+
+// supBookPath 把编码的外部路径转为可读形式
+func supBookPath(body []byte) string {
+    if len(body) < 4 {
+        return ""
+    }
+    cch := int(binary.LittleEndian.Uint16(body[2:]))
+    if cch == 0x0401 || cch == 0x3A01 || cch == 0 {
+        return ""
+    }
+    p := xlString(append(binary.LittleEndian.AppendUint16(nil, uint16(cch)), body[4:]...), 0)
+    r := strings.NewReplacer("\x01", "", "\x02", `\`, "\x03", `\`, "\x04", `..\`, "\x05", `\\`, "\x06", "", "\x07", "", "\x08", "")
+    if strings.HasPrefix(p, "\x01") && len(p) > 1 && p[1] != '@' {
+        p = p[1:2] + ":" + `\` + p[2:]
+    }
+    return r.Replace(p)
+}
+
+// cleanExcelBinary 清理 BIFF8 工作簿流：最后保存者、外部工作簿路径、共享保护用户和批注作者，
+// 返回流长度是否改变(需要重建复合文档)
+func cleanExcelBinary(f *cfbFile) (bool, error) {
+    wb := f.Root.find("Workbook")
+    if wb == nil {
+        return false, nil
+    }
+    data := append([]byte(nil), wb.Data...)
+    records := biffRecords(data)
+    for _, r := range records {
+        if r.typ == biffFilePass {
+            logPrintf("工作簿已加密，跳过 Excel 二进制清理")
+            return false, nil
+        }
+    }
+
+    // 批注作者和共享保护用户名缩短为一个空格，不保留原名的长度
+    bodies := map[int][]byte{}
+    notes := 0
+    for _, r := range records {
+        switch r.typ {
+        case biffWriteAccess:
+            // WRITEACCESS 固定为 112 字节，用户名清空后按 Excel 的习惯用空格填充
+            if len(r.body) >= 3 {
+                for i := range r.body {
+                    r.body[i] = ' '
+                }
+                r.body[0], r.body[1], r.body[2] = 0, 0, 0
+                logPrintf("清空 Excel 最后保存者")
+            }
+        case biffFileSharing:
+            if len(r.body) >= 4 {
+                if body, ok := shortenXLString(r.body, 4); ok {
+                    bodies[r.pos] = body
+                    logPrintf("清空 Excel 共享保护用户名")
+                }
+            }
+        case biffSupBook:
+            // 目录部分改为逐级上溯(..\)，只保留文件名，记录长度不变
+            if start, dirEnd, width, ok := supBookPathRange(r.body); ok {
+                logPrintf("清除外部工作簿路径: %s", supBookPath(r.body))
+                for i := start; i < dirEnd; i += width {
+                    r.body[i] = 0x04
+                    if width == 2 {
+                        r.body[i+1] = 0
+                    }
+                }
+            }
+        case biffNote:
+            if len(r.body) >= 8 {
+                if body, ok := shortenXLString(r.body, 8); ok {
+                    bodies[r.pos] = body
+                    notes++
+                }
+            }
+        }
+    }
+    if notes > 0 {
+        logPrintf("清空 Excel 批注作者 %d 个", notes)
+    }
+    if len(bodies) == 0 {
+        return false, f.writeStream(wb, data)
+    }
+    wb.Data = rebuildBiff(data, records, bodies)
+    return true, nil
+}
+
+func inspectExcelBinary(f *cfbFile) {
+    wb := f.Root.find("Workbook")
+    if wb == nil {
+        return
+    }
+    seen := map[string]bool{}
+    for _, r := range biffRecords(wb.Data) {
+        switch r.typ {
+        case biffFilePass:
+            reportPrintf("  工作簿已加密")
+            return
+        case biffWriteAccess:
+            if s := strings.TrimSpace(xlString(r.body, 0)); s != "" {
+                reportPrintf("  最后保存者: %s", s)
+            }
+        case biffFileSharing:
+            if len(r.body) >= 4 {
+                if s := strings.TrimSpace(xlString(r.body, 4)); s != "" {
+                    reportPrintf("  共享保护用户: %s", s)
+                }
+            }
+        case biffSupBook:
+            if p := supBookPath(r.body); p != "" {
+                reportPrintf("  外部工作簿: %s", p)
+            }
+        case biffNote:
+            if len(r.body) >= 8 {
+                if s := strings.TrimSpace(xlString(r.body, 8)); s != "" && !seen[s] {
+                    seen[s] = true
+                    reportPrintf("  批注作者: %s", s)
+                }
+            }
+        }
+    }
+}
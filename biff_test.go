@@ -0,0 +1,144 @@
+package main
+
+import (
+    "encoding/binary"
+    "testing"
+)
+
+const (
+    biffBOF         = 0x0809
+    biffEOF         = 0x000A
+    biffSST         = 0x00FC
+    biffDBCell      = 0x00D7
+    biffDefColWidth = 0x0055
+)
+
+func biffRec(typ uint16, body []byte) []byte {
+    b := binary.LittleEndian.AppendUint16(nil, typ)
+    b = binary.LittleEndian.AppendUint16(b, uint16(len(body)))
+    return append(b, body...)
+}
+
+func biffStr(s string) []byte {
+    b := binary.LittleEndian.AppendUint16(nil, uint16(len(s)))
+    b = append(b, 0)
+    return append(b, s...)
+}
+
+// biffTestStream 生成包含两个工作表的工作簿流，pos 为各偏移字段应指向的记录位置，
+// 为 nil 时偏移全部写 0
+func biffTestStream(pos map[string]uint32) []byte {
+    var w []byte
+    add := func(typ uint16, body []byte) {
+        w = append(w, biffRec(typ, body)...)
+    }
+    add(biffBOF, make([]byte, 16))
+    add(biffFileSharing, append([]byte{1, 0, 0x12, 0x34}, biffStr("Wang Wu")...))
+    add(biffBoundSheet, append(le32(pos["sheet1"]), biffStr("S1")...))
+    add(biffBoundSheet, append(le32(pos["sheet2"]), biffStr("S2")...))
+    add(biffSST, make([]byte, 8))
+    add(biffExtSST, append([]byte{8, 0}, le32(pos["sst"], 12)...))
+    add(biffEOF, nil)
+    for _, sheet := range []string{"sheet1", "sheet2"} {
+        add(biffBOF, make([]byte, 16))
+        add(biffIndex, le32(0, 0, 1, pos[sheet+".defcol"], pos[sheet+".dbcell"]))
+        add(biffDefColWidth, []byte{8, 0})
+        add(biffDBCell, le32(0))
+        add(biffNote, append(append(make([]byte, 8), biffStr("Li Si")...), 0))
+        add(biffEOF, nil)
+    }
+    return w
+}
+
+// biffTargets 返回各偏移字段应指向的记录位置和记录类型
+func biffTargets(w []byte) (map[string]uint32, map[string]uint16) {
+    pos := map[string]uint32{}
+    types := map[string]uint16{}
+    sheet := ""
+    for _, r := range biffRecords(w) {
+        name := ""
+        switch r.typ {
+        case biffBOF:
+            if sheet == "" {
+                sheet = "globals"
+                continue
+            }
+            if sheet == "globals" {
+                sheet = "sheet1"
+            } else {
+                sheet = "sheet2"
+            }
+            name = sheet
+        case biffSST:
+            name = "sst"
+        case biffDefColWidth:
+            name = sheet + ".defcol"
+        case biffDBCell:
+            name = sheet + ".dbcell"
+        default:
+            continue
+        }
+        pos[name] = uint32(r.pos)
+        types[name] = r.typ
+    }
+    return pos, types
+}
+
+func TestCleanExcelBinaryOffsets(t *testing.T) {
+    pos, types := biffTargets(biffTestStream(nil))
+    data := biffTestStream(pos)
+
+    root := &cfbEntry{Name: "Root Entry", Type: cfbTypeRoot, Children: []*cfbEntry{
+        {Name: "Workbook", Type: cfbTypeStream, Data: data},
+    }}
+    f, err := readCFB((&cfbFile{Root: root}).bytes())
+    if err != nil {
+        t.Fatal(err)
+    }
+    resized, err := cleanExcelBinary(f)
+    if err != nil {
+        t.Fatal(err)
+    }
+    out := f.Root.find("Workbook").Data
+    if !resized || len(out) >= len(data) {
+        t.Fatalf("流长度 %d，原始 %d，名称没有缩短", len(out), len(data))
+    }
+
+    // 记录位置按清理后的流重新计算，再与各偏移字段比较
+    want, _ := biffTargets(out)
+    got := map[string]uint32{}
+    sheet := 0
+    for _, r := range biffRecords(out) {
+        switch r.typ {
+        case biffBoundSheet:
+            sheet++
+            got[[]string{"", "sheet1", "sheet2"}[sheet]] = binary.LittleEndian.Uint32(r.body)
+        case biffExtSST:
+            got["sst"] = binary.LittleEndian.Uint32(r.body[2:])
+        case biffIndex:
+            name := "sheet1"
+            if _, ok := got["sheet1.defcol"]; ok {
+                name = "sheet2"
+            }
+            got[name+".defcol"] = binary.LittleEndian.Uint32(r.body[12:])
+            got[name+".dbcell"] = binary.LittleEndian.Uint32(r.body[16:])
+        case biffFileSharing:
+            if s := xlString(r.body, 4); s != " " {
+                t.Errorf("共享保护用户名为 %q", s)
+            }
+        case biffNote:
+            if s := xlString(r.body, 8); s != " " {
+                t.Errorf("批注作者为 %q", s)
+            }
+        }
+    }
+    for name, p := range want {
+        if got[name] != p {
+            t.Errorf("%s: 偏移 %d，应为 %d", name, got[name], p)
+            continue
+        }
+        if typ := binary.LittleEndian.Uint16(out[p:]); typ != types[name] {
+            t.Errorf("%s: 指向记录类型 %#04x，应为 %#04x", name, typ, types[name])
+        }
+    }
+}
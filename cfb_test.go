@@ -0,0 +1,102 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "testing"
+)
+
+func testStream(name string, size int) *cfbEntry {
+    data := make([]byte, size)
+    for i := range data {
+        data[i] = byte(i*7 + len(name))
+    }
+    return &cfbEntry{Name: name, Type: cfbTypeStream, Data: data}
+}
+
+func compareCFBEntries(t *testing.T, path string, want, got *cfbEntry) {
+    if got.Name != want.Name || got.Type != want.Type {
+        t.Errorf("%s: 读回 %q(类型 %d)，原始 %q(类型 %d)", path, got.Name, got.Type, want.Name, want.Type)
+        return
+    }
+    if !bytes.Equal(got.Data, want.Data) {
+        t.Errorf("%s: 读回 %d 字节，原始 %d 字节", path, len(got.Data), len(want.Data))
+    }
+    for _, w := range want.Children {
+        g := got.find(w.Name)
+        if g == nil {
+            t.Errorf("%s: 缺少 %s", path, w.Name)
+            continue
+        }
+        compareCFBEntries(t, path+"/"+w.Name, w, g)
+    }
+    if len(got.Children) != len(want.Children) {
+        t.Errorf("%s: 读回 %d 个子项，原始 %d 个", path, len(got.Children), len(want.Children))
+    }
+}
+
+func TestCFBRoundTrip(t *testing.T) {
+    vba := &cfbEntry{Name: "VBA", Type: cfbTypeStorage, Children: []*cfbEntry{
+        testStream("dir", 5000),
+        testStream("Module1", 50),
+    }}
+    root := &cfbEntry{Name: "Root Entry", Type: cfbTypeRoot, Children: []*cfbEntry{
+        testStream("\x05SummaryInformation", 200),
+        testStream("WordDocument", 4096),
+        testStream("Empty", 0),
+        vba,
+    }}
+    // 目录项和迷你流都超过一个扇区
+    for i := 0; i < 40; i++ {
+        root.Children = append(root.Children, testStream(fmt.Sprintf("Stream%02d", i), 100+i*90))
+    }
+
+    out := (&cfbFile{Root: root}).bytes()
+    f, err := readCFB(out)
+    if err != nil {
+        t.Fatal(err)
+    }
+    compareCFBEntries(t, "", root, f.Root)
+
+    // 读回后子项按目录树顺序排列，之后再重建结果应保持不变
+    out = f.bytes()
+    g, err := readCFB(out)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if again := g.bytes(); !bytes.Equal(again, out) {
+        t.Error("重建已重建的文件后内容改变")
+    }
+}
+
+func TestCFBWriteStream(t *testing.T) {
+    root := &cfbEntry{Name: "Root Entry", Type: cfbTypeRoot, Children: []*cfbEntry{
+        testStream("Mini", 300),
+        testStream("Big", 9000),
+    }}
+    f, err := readCFB((&cfbFile{Root: root}).bytes())
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, name := range []string{"Mini", "Big"} {
+        e := f.Root.find(name)
+        data := bytes.Repeat([]byte{0xAB}, len(e.Data))
+        if err := f.writeStream(e, data); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := f.writeStream(f.Root.find("Mini"), nil); err == nil {
+        t.Error("长度改变时应返回错误")
+    }
+
+    g, err := readCFB(f.raw)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, name := range []string{"Mini", "Big"} {
+        e := g.Root.find(name)
+        if !bytes.Equal(e.Data, bytes.Repeat([]byte{0xAB}, len(e.Data))) {
+            t.Errorf("%s: 原地写入的内容没有读回", name)
+        }
+    }
+}
@@ -3,10 +3,12 @@ package main
 import (
     "archive/zip"
     "fmt"
+    "hash/crc32"
     "io"
     "os"
     "flag"
     "path/filepath"
+    "sort"
     "strings"
     "sync"
     "time"
@@ -19,19 +21,72 @@ var officeExts = []string{
     ".doc", ".docx", ".docm", ".wps",
     ".et", ".xlsx", ".xls", ".xlsm",
     ".pps", ".ppt", ".pptx", ".pptm", ".dps",
+    ".odt", ".ods", ".odp",
 }
 
 var (
     enableBackup bool
     enableLog    bool
+    inspectOnly  bool
+    checkOnly    bool
     logFile      *os.File
     logMutex     sync.Mutex
 )
 
+// 清理选项
+var (
+    removeMacro     bool
+    cleanVba        bool
+    removeRevision  bool
+    resetView       bool
+    removeTag       bool
+    removeWebExt    bool
+    removeOrphans   bool
+    deterministic   bool
+    nativeLegacy    bool
+    rebuildCFB      bool
+    signaturePolicy string
+    connPolicy      string
+    pivotPolicy     string
+    hiddenPolicy    string
+    protectPolicy   string
+    protectPassword string
+    permPolicy      string
+    inkPolicy       string
+    altPolicy       string
+    fieldPolicy     string
+    linkPolicy      string
+    linkMapFile     string
+)
+
 func main() {
     showHelp := flag.Bool("h", false, "help")
     flag.BoolVar(&enableBackup, "b", false, "backup")
     flag.BoolVar(&enableLog, "l", false, "log")
+    flag.BoolVar(&removeMacro, "m", false, "remove macros")
+    flag.BoolVar(&cleanVba, "vba", false, "clean vba project metadata")
+    flag.BoolVar(&inspectOnly, "i", false, "inspect only")
+    flag.BoolVar(&checkOnly, "check", false, "validate package structure only")
+    flag.StringVar(&signaturePolicy, "sig", "remove", "signed package policy: remove|skip")
+    flag.StringVar(&connPolicy, "conn", "", "excel data connections: remove|sanitize")
+    flag.StringVar(&pivotPolicy, "pivot", "", "excel pivot caches: records|static")
+    flag.StringVar(&hiddenPolicy, "hidden", "", "hidden content: delete|unhide")
+    flag.BoolVar(&removeRevision, "rev", false, "remove shared workbook revisions")
+    flag.StringVar(&protectPolicy, "protect", "", "protection hashes: remove|reset")
+    flag.StringVar(&protectPassword, "pwd", "", "new password for -protect reset")
+    flag.StringVar(&permPolicy, "perm", "", "range permission editors: remove|anon")
+    flag.BoolVar(&resetView, "view", false, "reset view state and zoom")
+    flag.BoolVar(&removeTag, "tags", false, "remove powerpoint customer data tags")
+    flag.BoolVar(&removeWebExt, "webext", false, "remove web extension taskpanes and content add-ins")
+    flag.StringVar(&inkPolicy, "ink", "", "ink annotations: remove|strip")
+    flag.StringVar(&altPolicy, "alt", "", "picture names and alt text: files|all")
+    flag.StringVar(&fieldPolicy, "fields", "", "path and author fields: unlink|clear|remove")
+    flag.StringVar(&linkPolicy, "links", "", "external relationships: remove|unlink|map")
+    flag.StringVar(&linkMapFile, "linkmap", "", "rewrite rules for -links map")
+    flag.BoolVar(&removeOrphans, "orphans", false, "remove parts unreachable from package relationships")
+    flag.BoolVar(&deterministic, "det", false, "deterministic zip output")
+    flag.BoolVar(&nativeLegacy, "native", false, "clean legacy binary files in place without conversion")
+    flag.BoolVar(&rebuildCFB, "rebuild", false, "rebuild legacy compound files from live streams, zero-filling slack")
     flag.Parse()
 
     // 无路径参数 且 没有要求备份或日志 → 显示帮助
@@ -46,6 +101,49 @@ func main() {
         return
     }
 
+    // 策略参数只接受列出的取值，拼错时不能落入默认的删除分支
+    for _, p := range []struct {
+        name    string
+        value   string
+        allowed []string
+    }{
+        {"sig", signaturePolicy, []string{"remove", "skip"}},
+        {"conn", connPolicy, []string{"remove", "sanitize"}},
+        {"pivot", pivotPolicy, []string{"records", "static"}},
+        {"hidden", hiddenPolicy, []string{"delete", "unhide"}},
+        {"protect", protectPolicy, []string{"remove", "reset"}},
+        {"perm", permPolicy, []string{"remove", "anon"}},
+        {"ink", inkPolicy, []string{"remove", "strip"}},
+        {"alt", altPolicy, []string{"files", "all"}},
+        {"fields", fieldPolicy, []string{"unlink", "clear", "remove"}},
+        {"links", linkPolicy, []string{"remove", "unlink", "map"}},
+    } {
+        valid := p.value == "" && p.name != "sig"
+        for _, a := range p.allowed {
+            valid = valid || p.value == a
+        }
+        if !valid {
+            fmt.Printf("-%s 的取值无效: %q，可选 %s\n", p.name, p.value, strings.Join(p.allowed, "|"))
+            os.Exit(2)
+        }
+    }
+
+    if rebuildCFB {
+        nativeLegacy = true
+    }
+    if protectPolicy == "reset" && protectPassword == "" {
+        fmt.Println("-protect reset 需要用 -pwd 指定新密码")
+        os.Exit(2)
+    }
+    if linkPolicy == "map" {
+        rules, err := loadLinkRules(linkMapFile)
+        if err != nil {
+            fmt.Println("读取链接改写规则失败:", err)
+            os.Exit(2)
+        }
+        linkRules = rules
+    }
+
     var paths []string
     for _, arg := range flag.Args() {
         absPath, err := filepath.Abs(arg)
@@ -88,6 +186,26 @@ func main() {
         return
     }
 
+    // 仅检查，不修改文件
+    if inspectOnly {
+        for _, f := range files {
+            err := inspectFile(f)
+            if err != nil {
+                logPrintf("检查失败: %s, %v", f, err)
+            }
+        }
+        return
+    }
+    if checkOnly {
+        for _, f := range files {
+            err := checkFile(f)
+            if err != nil {
+                logPrintf("校验失败: %s, %v", f, err)
+            }
+        }
+        return
+    }
+
     // 备份
     if enableBackup {
         for _, f := range files {
@@ -108,6 +226,11 @@ func main() {
     for _, f := range files {
         logPrintf("处理文件: %s", f)
 
+        if nativeLegacy && isCFBFile(f) {
+            converted = append(converted, f)
+            continue
+        }
+
         cf, err := convertOldFile(f)
         if err != nil {
             logPrintf("转换失败: %s, %v", f, err)
@@ -337,12 +460,14 @@ func removePropertiesWithRetry(filePath string, retry int) error {
     if isZipFile(filePath) {
         for i := 0; i < retry; i++ {
             err = removeProperties(filePath)
-            if err == nil {
-                return nil
+            if err == nil || err == errSignedSkipped || err == errInvalidPackage || err == errOnlyMacroSheets {
+                return err
             }
             logPrintf("删除属性失败，重试 %d: %v", i+1, err)
             time.Sleep(1 * time.Second)
         }
+    } else if nativeLegacy && isCFBFile(filePath) {
+        err = removeLegacyProperties(filePath)
     } else {
         err = fmt.Errorf("警告: 文件不是OOXML格式，请确认文件格式！")
     }
@@ -365,6 +490,71 @@ func removeProperties(filePath string) error {
     tmpDir := filePath + "_tmp"
     os.MkdirAll(tmpDir, 0755)
 
+    err := extractPackage(filePath, tmpDir, func(name string) bool {
+        return strings.HasPrefix(name, "docProps/") || strings.HasPrefix(name, "customXml/")
+    })
+    if err != nil {
+        return err
+    }
+
+    odf := isODFPackage(tmpDir)
+    if (isSignedPackage(tmpDir) || odf && odfSigned(tmpDir)) && signaturePolicy == "skip" {
+        os.RemoveAll(tmpDir)
+        return errSignedSkipped
+    }
+
+    validate := validatePackage
+    if odf {
+        validate = validateODF
+    }
+    // 原文件本身的问题不算清理引入的，只比较清理前后新增的问题
+    baseline := validate(tmpDir)
+    if odf {
+        err = cleanODF(tmpDir)
+    } else {
+        err = cleanPackage(tmpDir)
+    }
+    if err != nil {
+        os.RemoveAll(tmpDir)
+        return err
+    }
+
+    // 校验不通过时不写回，原文件保持不变
+    if problems := newProblems(baseline, validate(tmpDir)); len(problems) > 0 {
+        for _, p := range problems {
+            logPrintf("校验失败: %s, %s", filePath, p)
+        }
+        os.RemoveAll(tmpDir)
+        return errInvalidPackage
+    }
+
+    target := filePath
+    if removeMacro {
+        target = macroFreeName(filePath)
+    }
+
+    // 先写临时文件，成功后再替换，避免写入中途出错损坏原文件
+    err = zipDir(tmpDir, target+".tmp")
+    if err == nil {
+        err = os.Rename(target+".tmp", target)
+    }
+    if err != nil {
+        os.Remove(target + ".tmp")
+        os.RemoveAll(tmpDir)
+        return err
+    }
+
+    if target != filePath {
+        os.Remove(filePath)
+        logPrintf("已删除宏并另存为: %s", target)
+    }
+
+    os.RemoveAll(tmpDir)
+    return nil
+}
+
+// extractPackage 解压 OOXML 包到 dir，skip 返回 true 的条目不解压
+func extractPackage(filePath, dir string, skip func(name string) bool) error {
     r, err := zip.OpenReader(filePath)
     if err != nil {
         return err
@@ -372,11 +562,11 @@ func removeProperties(filePath string) error {
     defer r.Close()
 
     for _, f := range r.File {
-        if strings.HasPrefix(f.Name, "docProps/") || strings.HasPrefix(f.Name, "customXml/") {
+        if skip != nil && skip(f.Name) {
             continue
         }
 
-        destPath := filepath.Join(tmpDir, f.Name)
+        destPath := filepath.Join(dir, f.Name)
         if f.FileInfo().IsDir() {
             os.MkdirAll(destPath, 0755)
             continue
@@ -401,48 +591,183 @@ func removeProperties(filePath string) error {
             return err
         }
     }
+    return nil
+}
 
-    err = zipDir(tmpDir, filePath)
-    if err != nil {
-        return err
+// cleanPackage 对解压后的 OOXML 包执行按参数启用的清理项
+func cleanPackage(dir string) error {
+    if isSignedPackage(dir) {
+        if err := removeSignatures(dir); err != nil {
+            return err
+        }
     }
-
-    os.RemoveAll(tmpDir)
-    return nil
+    if removeMacro {
+        if err := removeMacros(dir); err != nil {
+            return err
+        }
+    }
+    if cleanVba {
+        if err := cleanVbaProjects(dir); err != nil {
+            return err
+        }
+    }
+    if connPolicy != "" {
+        if err := cleanConnections(dir, connPolicy); err != nil {
+            return err
+        }
+    }
+    if pivotPolicy != "" {
+        if err := cleanPivotCaches(dir, pivotPolicy); err != nil {
+            return err
+        }
+    }
+    if hiddenPolicy != "" {
+        if err := cleanHiddenContent(dir, hiddenPolicy); err != nil {
+            return err
+        }
+    }
+    if removeRevision {
+        if err := removeRevisions(dir); err != nil {
+            return err
+        }
+    }
+    if protectPolicy != "" {
+        if err := cleanProtection(dir, protectPolicy, protectPassword); err != nil {
+            return err
+        }
+    }
+    if permPolicy != "" {
+        if err := cleanPermissions(dir, permPolicy); err != nil {
+            return err
+        }
+    }
+    if resetView {
+        if err := normalizeViews(dir); err != nil {
+            return err
+        }
+    }
+    if removeTag {
+        if err := removeTags(dir); err != nil {
+            return err
+        }
+    }
+    if removeWebExt {
+        if err := removeWebExtensions(dir); err != nil {
+            return err
+        }
+    }
+    if inkPolicy != "" {
+        if err := cleanInk(dir, inkPolicy); err != nil {
+            return err
+        }
+    }
+    if altPolicy != "" {
+        if err := cleanAltText(dir, altPolicy); err != nil {
+            return err
+        }
+    }
+    if fieldPolicy != "" {
+        if err := cleanPathFields(dir, fieldPolicy); err != nil {
+            return err
+        }
+    }
+    if linkPolicy != "" {
+        if err := cleanExternalLinks(dir, linkPolicy); err != nil {
+            return err
+        }
+    }
+    if removeOrphans {
+        if err := removeOrphanParts(dir); err != nil {
+            return err
+        }
+    }
+    return pruneMissingParts(dir)
 }
 
+// zipDir 把 source 目录打包为 target，zip 目录区和文件都写入成功才返回 nil
 func zipDir(source, target string) error {
     outFile, err := os.Create(target)
     if err != nil {
         return err
     }
-    defer outFile.Close()
 
     zw := zip.NewWriter(outFile)
-    defer zw.Close()
-
-    return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
-        if info.IsDir() {
-            return nil
-        }
+    err = writeZipEntries(zw, source)
+    // Close 才写出中央目录，错误不能忽略，否则会留下截断的文件
+    if cerr := zw.Close(); err == nil {
+        err = cerr
+    }
+    if cerr := outFile.Close(); err == nil {
+        err = cerr
+    }
+    return err
+}
 
-        relPath, err := filepath.Rel(source, path)
+func writeZipEntries(zw *zip.Writer, source string) error {
+    var files []string
+    err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
         if err != nil {
             return err
         }
+        if !info.IsDir() {
+            relPath, err := filepath.Rel(source, path)
+            if err != nil {
+                return err
+            }
+            files = append(files, filepath.ToSlash(relPath))
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    // 固定模式: [Content_Types].xml 在前，其余按名称排序，时间统一为 1980-01-01 00:00:00
+    if deterministic {
+        sort.Slice(files, func(i, j int) bool {
+            if (files[i] == contentTypesPart) != (files[j] == contentTypesPart) {
+                return files[i] == contentTypesPart
+            }
+            return files[i] < files[j]
+        })
+    }
 
-        f, err := zw.Create(relPath)
+    // OpenDocument 要求 mimetype 为第一个条目且不压缩
+    for i, name := range files {
+        if name == odfMimetypePart {
+            copy(files[1:i+1], files[:i])
+            files[0] = name
+            break
+        }
+    }
+
+    for _, name := range files {
+        data, err := os.ReadFile(filepath.Join(source, filepath.FromSlash(name)))
         if err != nil {
             return err
         }
-
-        srcFile, err := os.Open(path)
+        header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+        if deterministic {
+            // 直接写 MS-DOS 日期，设置 Modified 会额外写入扩展时间戳字段
+            header.ModifiedDate = 1<<5 | 1
+        }
+        create := zw.CreateHeader
+        if name == odfMimetypePart {
+            // 不压缩，且本地文件头直接带上校验和与长度，不使用数据描述符
+            header.Method = zip.Store
+            header.CRC32 = crc32.ChecksumIEEE(data)
+            header.CompressedSize64 = uint64(len(data))
+            header.UncompressedSize64 = uint64(len(data))
+            header.CreatorVersion, header.ReaderVersion = 20, 20
+            create = zw.CreateRaw
+        }
+        f, err := create(header)
         if err != nil {
             return err
         }
-        defer srcFile.Close()
-
-        _, err = io.Copy(f, srcFile)
-        return err
-    })
+        if _, err := f.Write(data); err != nil {
+            return err
+        }
+    }
+    return nil
 }
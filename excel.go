@@ -0,0 +1,802 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+const (
+    relOfficeDoc   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+    relWorksheet   = relOfficeDoc + "worksheet"
+    relConnections = relOfficeDoc + "connections"
+    relQueryTable  = relOfficeDoc + "queryTable"
+    relTable       = relOfficeDoc + "table"
+)
+
+var (
+    connectionRe        = regexp.MustCompile(`(?s)<connection\b[^>]*?(?:/>|>.*?</connection>)`)
+    connectionTag       = regexp.MustCompile(`<connection\b[^>]*>`)
+    dbPrRe              = regexp.MustCompile(`<dbPr\b[^>]*>`)
+    webPrRe             = regexp.MustCompile(`<webPr\b[^>]*>`)
+    tableTagRe          = regexp.MustCompile(`<table\b[^>]*>`)
+    tableColumnRe       = regexp.MustCompile(`<tableColumn\b[^>]*>`)
+    connectionIDRe      = regexp.MustCompile(`\sconnectionId\s*=\s*"(\d+)"`)
+    credentialRe        = regexp.MustCompile(`(?i)(^|;)\s*(password|pwd|user id|uid|user name|username|user)\s*=\s*("[^"]*"|'[^']*'|\{[^}]*\}|[^;]*)`)
+    urlUserInfoRe       = regexp.MustCompile(`(?i)\b((?:https?|ftp)://)[^/@\s]+@`)
+    repeatedSemicolonRe = regexp.MustCompile(`;{2,}`)
+)
+
+// workbookPart 返回工作簿主部件，非 Excel 包返回空
+func workbookPart(dir string) string {
+    main := mainDocumentPart(dir)
+    if !strings.HasPrefix(main, "xl/") {
+        return ""
+    }
+    return main
+}
+
+func worksheetParts(dir string) []string {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return nil
+    }
+    return findRelsByType(dir, wb, relWorksheet)
+}
+
+// partsByRelType 返回包内所有关系文件中指定类型的内部目标
+func partsByRelType(dir, relType string) []string {
+    seen := map[string]bool{}
+    var parts []string
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        for _, r := range readRels(dir, rels) {
+            if r.Type != relType || r.external() {
+                continue
+            }
+            t := resolveTarget(source, r.Target)
+            if !seen[t] {
+                seen[t] = true
+                parts = append(parts, t)
+            }
+        }
+    }
+    return parts
+}
+
+// stripCredentials 去掉连接字符串中的用户名和密码
+func stripCredentials(s string) (string, bool) {
+    found := credentialRe.MatchString(s) || urlUserInfoRe.MatchString(s)
+    s = credentialRe.ReplaceAllString(s, "$1")
+    s = strings.TrimLeft(repeatedSemicolonRe.ReplaceAllString(s, ";"), ";")
+    s = urlUserInfoRe.ReplaceAllString(s, "$1")
+    return s, found
+}
+
+// sanitizeConnection 去掉单个 <connection> 元素中的凭据，保留其余结构
+func sanitizeConnection(conn string) (string, bool) {
+    found := false
+    fix := func(re *regexp.Regexp, attr string) {
+        conn = re.ReplaceAllStringFunc(conn, func(tag string) string {
+            v := xmlAttr(tag, attr)
+            if v == "" {
+                return tag
+            }
+            clean, ok := stripCredentials(xmlUnescape(v))
+            if !ok {
+                return tag
+            }
+            found = true
+            return setXMLAttr(tag, attr, xmlEscape(clean))
+        })
+    }
+    fix(dbPrRe, "connection")
+    fix(webPrRe, "url")
+    conn = connectionTag.ReplaceAllStringFunc(conn, func(tag string) string {
+        if xmlAttr(tag, "savePassword") != "" {
+            found = true
+        }
+        return removeXMLAttr(tag, "savePassword")
+    })
+    return conn, found
+}
+
+// referencedConnectionIDs 查找数据透视缓存、切片器等仍在使用的连接
+func referencedConnectionIDs(dir, connPart string) map[string]bool {
+    ids := map[string]bool{}
+    for _, p := range listParts(dir) {
+        if p == connPart || !strings.HasPrefix(p, "xl/") || !strings.HasSuffix(p, ".xml") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        for _, m := range connectionIDRe.FindAllStringSubmatch(string(data), -1) {
+            ids[m[1]] = true
+        }
+    }
+    return ids
+}
+
+// cleanConnections 按策略处理 Excel 数据连接和查询表
+// remove: 删除查询表及未被其他对象引用的连接，单元格保留当前数值
+// sanitize: 只去掉连接字符串中的账号密码
+func cleanConnections(dir, policy string) error {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return nil
+    }
+
+    if policy == "remove" {
+        for _, qt := range partsByRelType(dir, relQueryTable) {
+            logPrintf("删除查询表: %s", qt)
+            if err := removePart(dir, qt); err != nil {
+                return err
+            }
+        }
+        for _, t := range partsByRelType(dir, relTable) {
+            data, err := readPart(dir, t)
+            if err != nil {
+                continue
+            }
+            text := tableTagRe.ReplaceAllStringFunc(string(data), func(tag string) string {
+                if xmlAttr(tag, "tableType") != "queryTable" {
+                    return tag
+                }
+                return removeXMLAttr(removeXMLAttr(tag, "tableType"), "connectionId")
+            })
+            text = tableColumnRe.ReplaceAllStringFunc(text, func(tag string) string {
+                return removeXMLAttr(tag, "queryTableFieldId")
+            })
+            if err := writePart(dir, t, []byte(text)); err != nil {
+                return err
+            }
+        }
+    }
+
+    for _, connPart := range findRelsByType(dir, wb, relConnections) {
+        data, err := readPart(dir, connPart)
+        if err != nil {
+            continue
+        }
+        var referenced map[string]bool
+        if policy == "remove" {
+            referenced = referencedConnectionIDs(dir, connPart)
+        }
+        remaining := 0
+        text := connectionRe.ReplaceAllStringFunc(string(data), func(conn string) string {
+            name := xmlUnescape(xmlAttr(conn, "name"))
+            if policy == "remove" && !referenced[xmlAttr(conn, "id")] {
+                logPrintf("删除数据连接: %s", name)
+                return ""
+            }
+            remaining++
+            clean, found := sanitizeConnection(conn)
+            if found {
+                logPrintf("清除数据连接凭据: %s", name)
+            }
+            return clean
+        })
+        if remaining == 0 {
+            if err := removePart(dir, connPart); err != nil {
+                return err
+            }
+            continue
+        }
+        if err := writePart(dir, connPart, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectConnections(dir string) {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return
+    }
+    for _, connPart := range findRelsByType(dir, wb, relConnections) {
+        data, err := readPart(dir, connPart)
+        if err != nil {
+            continue
+        }
+        for _, conn := range connectionRe.FindAllString(string(data), -1) {
+            name := xmlUnescape(xmlAttr(conn, "name"))
+            var detail []string
+            for _, m := range dbPrRe.FindAllString(conn, -1) {
+                detail = append(detail, xmlUnescape(xmlAttr(m, "connection")))
+            }
+            for _, m := range webPrRe.FindAllString(conn, -1) {
+                detail = append(detail, xmlUnescape(xmlAttr(m, "url")))
+            }
+            _, found := sanitizeConnection(conn)
+            for i, d := range detail {
+                detail[i], _ = stripCredentials(d)
+            }
+            note := ""
+            if found {
+                note = " (含账号/密码)"
+            }
+            reportPrintf("  数据连接: %s%s", name, note)
+            for _, d := range detail {
+                reportPrintf("    %s", d)
+            }
+        }
+    }
+    for _, qt := range partsByRelType(dir, relQueryTable) {
+        reportPrintf("  查询表: %s", qt)
+    }
+}
+
+const (
+    relPivotCacheDef     = relOfficeDoc + "pivotCacheDefinition"
+    relPivotCacheRecords = relOfficeDoc + "pivotCacheRecords"
+    relPivotTable        = relOfficeDoc + "pivotTable"
+    relSlicerCache       = "http://schemas.microsoft.com/office/2007/relationships/slicerCache"
+    relTimelineCache     = "http://schemas.microsoft.com/office/2011/relationships/timelineCache"
+)
+
+var (
+    pivotCacheDefTag   = regexp.MustCompile(`<pivotCacheDefinition\b[^>]*>`)
+    pivotCacheRecTag   = regexp.MustCompile(`<pivotCacheRecords\b[^>]*>`)
+    worksheetSourceTag = regexp.MustCompile(`<worksheetSource\b[^>]*>`)
+    pivotCacheRe       = regexp.MustCompile(`(?s)<pivotCache\b[^>]*?(?:/>|>.*?</pivotCache>)`)
+    pivotCachesEmptyRe = regexp.MustCompile(`<pivotCaches\s*/>|<pivotCaches>\s*</pivotCaches>`)
+    chartPivotRe       = regexp.MustCompile(`(?s)<c:pivotSource>.*?</c:pivotSource>|<c:pivotFmts>.*?</c:pivotFmts>|<c:pivotFmts\s*/>|<c14:pivotOptions>.*?</c14:pivotOptions>`)
+)
+
+// cleanPivotCaches 按策略处理数据透视表
+// records: 删除缓存明细记录，并标记缓存需刷新
+// static: 删除数据透视表和缓存，单元格保留当前显示的数值
+func cleanPivotCaches(dir, policy string) error {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return nil
+    }
+    if policy == "static" {
+        if len(findRelsByType(dir, wb, relSlicerCache)) > 0 || len(findRelsByType(dir, wb, relTimelineCache)) > 0 {
+            logPrintf("工作簿含切片器或日程表，数据透视表改为仅删除缓存明细")
+            policy = "records"
+        }
+    }
+
+    if policy == "static" {
+        for _, pt := range partsByRelType(dir, relPivotTable) {
+            logPrintf("删除数据透视表: %s", pt)
+            if err := removePart(dir, pt); err != nil {
+                return err
+            }
+        }
+        for _, def := range findRelsByType(dir, wb, relPivotCacheDef) {
+            logPrintf("删除数据透视缓存: %s", def)
+            if err := removePartTree(dir, def); err != nil {
+                return err
+            }
+        }
+        if err := removeDanglingPivotCaches(dir, wb); err != nil {
+            return err
+        }
+        for _, p := range listParts(dir) {
+            if !strings.HasPrefix(p, "xl/charts/") || !strings.HasSuffix(p, ".xml") {
+                continue
+            }
+            data, err := readPart(dir, p)
+            if err != nil || !chartPivotRe.Match(data) {
+                continue
+            }
+            if err := writePart(dir, p, chartPivotRe.ReplaceAll(data, nil)); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    for _, def := range findRelsByType(dir, wb, relPivotCacheDef) {
+        records := findRelsByType(dir, def, relPivotCacheRecords)
+        if len(records) == 0 {
+            continue
+        }
+        for _, rec := range records {
+            logPrintf("删除数据透视缓存明细: %s", rec)
+            if err := removePart(dir, rec); err != nil {
+                return err
+            }
+        }
+        data, err := readPart(dir, def)
+        if err != nil {
+            continue
+        }
+        text := pivotCacheDefTag.ReplaceAllStringFunc(string(data), func(tag string) string {
+            tag = removeXMLAttr(removeXMLAttr(tag, "r:id"), "refreshedBy")
+            tag = setXMLAttr(tag, "saveData", "0")
+            return setXMLAttr(tag, "invalid", "1")
+        })
+        if err := writePart(dir, def, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// removeDanglingPivotCaches 删除 workbook.xml 中关系已不存在的 <pivotCache>
+func removeDanglingPivotCaches(dir, wb string) error {
+    ids := map[string]bool{}
+    for _, r := range readRels(dir, relsPartFor(wb)) {
+        ids[r.ID] = true
+    }
+    data, err := readPart(dir, wb)
+    if err != nil {
+        return err
+    }
+    text := pivotCacheRe.ReplaceAllStringFunc(string(data), func(tag string) string {
+        if ids[xmlAttr(tag, "r:id")] {
+            return tag
+        }
+        return ""
+    })
+    text = pivotCachesEmptyRe.ReplaceAllString(text, "")
+    return writePart(dir, wb, []byte(text))
+}
+
+func inspectPivotCaches(dir string) {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return
+    }
+    for _, def := range findRelsByType(dir, wb, relPivotCacheDef) {
+        source := "外部数据"
+        if data, err := readPart(dir, def); err == nil {
+            if m := worksheetSourceTag.FindString(string(data)); m != "" {
+                source = xmlUnescape(xmlAttr(m, "sheet") + "!" + xmlAttr(m, "ref"))
+                if name := xmlAttr(m, "name"); name != "" {
+                    source = xmlUnescape(name)
+                }
+            }
+        }
+        for _, rec := range findRelsByType(dir, def, relPivotCacheRecords) {
+            count := "?"
+            if data, err := readPart(dir, rec); err == nil {
+                if m := pivotCacheRecTag.FindString(string(data)); m != "" && xmlAttr(m, "count") != "" {
+                    count = xmlAttr(m, "count")
+                }
+            }
+            reportPrintf("  数据透视缓存明细: %s, %s 行, 来源 %s", rec, count, source)
+        }
+    }
+}
+
+const relCalcChain = relOfficeDoc + "calcChain"
+
+var (
+    sheetTagRe     = regexp.MustCompile(`<sheet\b[^>]*>`)
+    definedNameRe  = regexp.MustCompile(`(?s)<definedName\b[^>]*?(?:/>|>.*?</definedName>)`)
+    definedNameTag = regexp.MustCompile(`<definedName\b[^>]*>`)
+    rowTagRe       = regexp.MustCompile(`<row\b[^>]*>`)
+    colTagRe       = regexp.MustCompile(`<col\b[^>]*>`)
+    bookViewTagRe  = regexp.MustCompile(`<workbookView\b[^>]*>`)
+    formulaElemRe  = regexp.MustCompile(`(?s)(<(?:f|formula|formula1|formula2|c:f|xm:f)\b[^>]*>)(.*?)(</(?:f|formula|formula1|formula2|c:f|xm:f)>)`)
+)
+
+type sheetInfo struct {
+    Name  string
+    State string
+    Part  string
+    tag   string
+
+    relType string
+}
+
+// workbookSheets 按 workbook.xml 中的顺序返回工作表，下标即 localSheetId
+func workbookSheets(dir, wb string) []sheetInfo {
+    data, err := readPart(dir, wb)
+    if err != nil {
+        return nil
+    }
+    targets := map[string]string{}
+    types := map[string]string{}
+    for _, r := range readRels(dir, relsPartFor(wb)) {
+        targets[r.ID] = resolveTarget(wb, r.Target)
+        types[r.ID] = r.Type
+    }
+    var sheets []sheetInfo
+    for _, tag := range sheetTagRe.FindAllString(string(data), -1) {
+        sheets = append(sheets, sheetInfo{
+            Name:  xmlUnescape(xmlAttr(tag, "name")),
+            State: xmlAttr(tag, "state"),
+            Part:  targets[xmlAttr(tag, "r:id")],
+            tag:   tag,
+
+            relType: types[xmlAttr(tag, "r:id")],
+        })
+    }
+    return sheets
+}
+
+func (s sheetInfo) hidden() bool {
+    return s.State == "hidden" || s.State == "veryHidden"
+}
+
+func isBuiltinName(name string) bool {
+    return strings.HasPrefix(name, "_xlnm.")
+}
+
+// sheetRefRe 匹配引用指定工作表的单元格、区域或名称，如 Sheet1!A1、'My Sheet'!$A:$B
+func sheetRefRe(name string) *regexp.Regexp {
+    quoted := regexp.QuoteMeta(xmlEscape("'" + strings.ReplaceAll(name, "'", "''") + "'"))
+    quotedApos := regexp.QuoteMeta("&apos;" + strings.ReplaceAll(xmlEscape(name), "'", "&apos;&apos;") + "&apos;")
+    bare := regexp.QuoteMeta(xmlEscape(name))
+    ref := `(?:\$?[A-Za-z]{1,3}\$?\d+(?::\$?[A-Za-z]{1,3}\$?\d+)?|\$?[A-Za-z]{1,3}:\$?[A-Za-z]{1,3}|\$?\d+:\$?\d+|[A-Za-z_\\][\w.]*)`
+    return regexp.MustCompile(`(^|[^\w'.;])(?:` + quoted + `|` + quotedApos + `|` + bare + `)!` + ref)
+}
+
+// replaceSheetRefs 把公式中对已删除工作表的引用替换为 #REF!
+func replaceSheetRefs(text string, refs []*regexp.Regexp) string {
+    return formulaElemRe.ReplaceAllStringFunc(text, func(elem string) string {
+        m := formulaElemRe.FindStringSubmatch(elem)
+        body := m[2]
+        for _, re := range refs {
+            body = re.ReplaceAllString(body, "${1}#REF!")
+        }
+        return m[1] + body + m[3]
+    })
+}
+
+// cleanHiddenSheets 按策略处理工作簿中的隐藏内容
+// delete: 删除隐藏和深度隐藏的工作表及隐藏名称，修正名称、公式和视图中的引用
+// unhide: 取消工作表、行、列和名称的隐藏，便于人工复查
+func cleanHiddenSheets(dir, policy string) error {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return nil
+    }
+    data, err := readPart(dir, wb)
+    if err != nil {
+        return err
+    }
+    sheets := workbookSheets(dir, wb)
+
+    if policy == "unhide" {
+        text := sheetTagRe.ReplaceAllStringFunc(string(data), func(tag string) string {
+            return removeXMLAttr(tag, "state")
+        })
+        text = definedNameTag.ReplaceAllStringFunc(text, func(tag string) string {
+            if isBuiltinName(xmlAttr(tag, "name")) {
+                return tag
+            }
+            return removeXMLAttr(tag, "hidden")
+        })
+        if err := writePart(dir, wb, []byte(text)); err != nil {
+            return err
+        }
+        for _, s := range sheets {
+            sheetData, err := readPart(dir, s.Part)
+            if err != nil {
+                continue
+            }
+            unhide := func(tag string) string { return removeXMLAttr(tag, "hidden") }
+            text := rowTagRe.ReplaceAllStringFunc(string(sheetData), unhide)
+            text = colTagRe.ReplaceAllStringFunc(text, unhide)
+            if err := writePart(dir, s.Part, []byte(text)); err != nil {
+                return err
+            }
+        }
+        logPrintf("已取消隐藏工作表、行、列和名称")
+        return nil
+    }
+
+    hidden := false
+    for _, s := range sheets {
+        hidden = hidden || s.hidden()
+    }
+    if !hidden {
+        return nil
+    }
+    deleted, err := deleteSheets(dir, wb, sheetInfo.hidden, func(tag string) bool {
+        return xmlAttr(tag, "hidden") == "1" && !isBuiltinName(xmlAttr(tag, "name"))
+    })
+    if err == nil && !deleted {
+        logPrintf("工作簿没有可见工作表，跳过删除隐藏工作表")
+    }
+    return err
+}
+
+// deleteSheets 删除满足 drop 的工作表及 dropName 选中的名称，修正名称、公式和视图中的引用，
+// 没有要删除的工作表或删除后没有剩余工作表时不做修改并返回 false
+func deleteSheets(dir, wb string, drop func(sheetInfo) bool, dropName func(tag string) bool) (bool, error) {
+    data, err := readPart(dir, wb)
+    if err != nil {
+        return false, err
+    }
+    sheets := workbookSheets(dir, wb)
+
+    // 新旧工作表下标映射，-1 表示已删除
+    newIndex := make([]int, len(sheets))
+    var refs []*regexp.Regexp
+    kept := 0
+    for i, s := range sheets {
+        if drop(s) {
+            newIndex[i] = -1
+            refs = append(refs, sheetRefRe(s.Name))
+            continue
+        }
+        newIndex[i] = kept
+        kept++
+    }
+    if len(refs) == 0 || kept == 0 {
+        return false, nil
+    }
+
+    text := string(data)
+    for i, s := range sheets {
+        if newIndex[i] >= 0 {
+            continue
+        }
+        if s.State != "" {
+            logPrintf("删除工作表: %s (%s)", s.Name, s.State)
+        } else {
+            logPrintf("删除工作表: %s", s.Name)
+        }
+        text = strings.Replace(text, s.tag, "", 1)
+        if s.Part != "" {
+            if err := removePartTree(dir, s.Part); err != nil {
+                return false, err
+            }
+        }
+    }
+
+    text = definedNameRe.ReplaceAllStringFunc(text, func(elem string) string {
+        tag := definedNameTag.FindString(elem)
+        name := xmlAttr(tag, "name")
+        if dropName(tag) {
+            logPrintf("删除名称: %s", xmlUnescape(name))
+            return ""
+        }
+        if id := xmlAttr(tag, "localSheetId"); id != "" {
+            var old int
+            fmt.Sscanf(id, "%d", &old)
+            if old >= 0 && old < len(newIndex) {
+                if newIndex[old] < 0 {
+                    return ""
+                }
+                elem = strings.Replace(elem, tag, setXMLAttr(tag, "localSheetId", fmt.Sprint(newIndex[old])), 1)
+            }
+        }
+        body := strings.TrimPrefix(elem, tag)
+        for _, re := range refs {
+            body = re.ReplaceAllString(body, "${1}#REF!")
+        }
+        return tag + body
+    })
+    text = strings.Replace(text, "<definedNames></definedNames>", "", 1)
+
+    text = bookViewTagRe.ReplaceAllStringFunc(text, func(tag string) string {
+        for _, attr := range []string{"activeTab", "firstSheet"} {
+            v := xmlAttr(tag, attr)
+            if v == "" {
+                continue
+            }
+            var old int
+            fmt.Sscanf(v, "%d", &old)
+            n := 0
+            if old >= 0 && old < len(newIndex) && newIndex[old] >= 0 {
+                n = newIndex[old]
+            }
+            tag = setXMLAttr(tag, attr, fmt.Sprint(n))
+        }
+        return tag
+    })
+    text = replaceSheetRefs(text, refs)
+    if err := writePart(dir, wb, []byte(text)); err != nil {
+        return false, err
+    }
+
+    // 其余工作表和图表中的公式引用
+    for _, p := range listParts(dir) {
+        if !strings.HasPrefix(p, "xl/") || !strings.HasSuffix(p, ".xml") || p == wb {
+            continue
+        }
+        partData, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        fixed := replaceSheetRefs(string(partData), refs)
+        if fixed != string(partData) {
+            if err := writePart(dir, p, []byte(fixed)); err != nil {
+                return false, err
+            }
+        }
+    }
+
+    // 计算链按工作表编号记录单元格，删除后由 Excel 重建
+    for _, cc := range findRelsByType(dir, wb, relCalcChain) {
+        if err := removePart(dir, cc); err != nil {
+            return false, err
+        }
+    }
+    return true, nil
+}
+
+func columnName(n int) string {
+    name := ""
+    for n > 0 {
+        n--
+        name = string(rune('A'+n%26)) + name
+        n /= 26
+    }
+    return name
+}
+
+func inspectHiddenSheets(dir string) {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return
+    }
+    for _, s := range workbookSheets(dir, wb) {
+        if s.hidden() {
+            reportPrintf("  隐藏工作表: %s (%s)", s.Name, s.State)
+        }
+        sheetData, err := readPart(dir, s.Part)
+        if err != nil {
+            continue
+        }
+        var rows []string
+        for _, tag := range rowTagRe.FindAllString(string(sheetData), -1) {
+            if xmlAttr(tag, "hidden") == "1" || xmlAttr(tag, "hidden") == "true" {
+                rows = append(rows, xmlAttr(tag, "r"))
+            }
+        }
+        if len(rows) > 0 {
+            list := rows
+            if len(list) > 20 {
+                list = append(list[:20:20], "...")
+            }
+            reportPrintf("  隐藏行: %s 共 %d 行 (%s)", s.Name, len(rows), strings.Join(list, ","))
+        }
+        var cols []string
+        for _, tag := range colTagRe.FindAllString(string(sheetData), -1) {
+            if xmlAttr(tag, "hidden") != "1" && xmlAttr(tag, "hidden") != "true" {
+                continue
+            }
+            var min, max int
+            fmt.Sscanf(xmlAttr(tag, "min"), "%d", &min)
+            fmt.Sscanf(xmlAttr(tag, "max"), "%d", &max)
+            cols = append(cols, columnName(min)+":"+columnName(max))
+        }
+        if len(cols) > 0 {
+            reportPrintf("  隐藏列: %s (%s)", s.Name, strings.Join(cols, ","))
+        }
+    }
+    data, err := readPart(dir, wb)
+    if err != nil {
+        return
+    }
+    for _, elem := range definedNameRe.FindAllString(string(data), -1) {
+        tag := definedNameTag.FindString(elem)
+        name := xmlAttr(tag, "name")
+        if xmlAttr(tag, "hidden") == "1" && !isBuiltinName(name) {
+            body := strings.TrimSuffix(strings.TrimPrefix(elem, tag), "</definedName>")
+            reportPrintf("  隐藏名称: %s = %s", xmlUnescape(name), xmlUnescape(body))
+        }
+    }
+}
+
+const (
+    relRevisionHeaders = relOfficeDoc + "revisionHeaders"
+    relUsernames       = relOfficeDoc + "usernames"
+)
+
+var (
+    userInfoTagRe      = regexp.MustCompile(`<userInfo\b[^>]*>`)
+    revisionHeaderRe   = regexp.MustCompile(`<header\b[^>]*>`)
+    workbookProtectRe  = regexp.MustCompile(`<workbookProtection\b[^>]*>`)
+    customBookViewRe   = regexp.MustCompile(`(?s)<customWorkbookView\b[^>]*?(?:/>|>.*?</customWorkbookView>)`)
+    customSheetViewRe  = regexp.MustCompile(`(?s)<customSheetView\b[^>]*?(?:/>|>.*?</customSheetView>)`)
+    emptyCustomViewsRe = regexp.MustCompile(`<(customWorkbookViews|customSheetViews)>\s*</(?:customWorkbookViews|customSheetViews)>`)
+)
+
+// 共享工作簿修订保护相关属性
+var revisionProtectAttrs = []string{
+    "lockRevision", "revisionsPassword", "revisionsAlgorithmName",
+    "revisionsHashValue", "revisionsSaltValue", "revisionsSpinCount",
+}
+
+// removeRevisions 删除共享工作簿的修订记录、用户名列表和个人视图，并取消共享修订保护
+func removeRevisions(dir string) error {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return nil
+    }
+    for _, h := range findRelsByType(dir, wb, relRevisionHeaders) {
+        logPrintf("删除修订记录: %s", h)
+        if err := removePartTree(dir, h); err != nil {
+            return err
+        }
+    }
+    for _, u := range findRelsByType(dir, wb, relUsernames) {
+        logPrintf("删除共享用户列表: %s", u)
+        if err := removePart(dir, u); err != nil {
+            return err
+        }
+    }
+
+    data, err := readPart(dir, wb)
+    if err != nil {
+        return err
+    }
+    // 个人视图以用户名命名，同时删除各工作表中对应的视图
+    personal := map[string]bool{}
+    text := customBookViewRe.ReplaceAllStringFunc(string(data), func(elem string) string {
+        if xmlAttr(elem, "personalView") != "1" && xmlAttr(elem, "personalView") != "true" {
+            return elem
+        }
+        personal[xmlAttr(elem, "guid")] = true
+        return ""
+    })
+    text = emptyCustomViewsRe.ReplaceAllString(text, "")
+    text = workbookProtectRe.ReplaceAllStringFunc(text, func(tag string) string {
+        for _, attr := range revisionProtectAttrs {
+            tag = removeXMLAttr(tag, attr)
+        }
+        return tag
+    })
+    if err := writePart(dir, wb, []byte(text)); err != nil {
+        return err
+    }
+
+    if len(personal) == 0 {
+        return nil
+    }
+    for _, s := range worksheetParts(dir) {
+        sheetData, err := readPart(dir, s)
+        if err != nil {
+            continue
+        }
+        text := customSheetViewRe.ReplaceAllStringFunc(string(sheetData), func(elem string) string {
+            if personal[xmlAttr(elem, "guid")] {
+                return ""
+            }
+            return elem
+        })
+        text = emptyCustomViewsRe.ReplaceAllString(text, "")
+        if err := writePart(dir, s, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectRevisions(dir string) {
+    wb := workbookPart(dir)
+    if wb == "" {
+        return
+    }
+    for _, u := range findRelsByType(dir, wb, relUsernames) {
+        data, err := readPart(dir, u)
+        if err != nil {
+            continue
+        }
+        var names []string
+        for _, tag := range userInfoTagRe.FindAllString(string(data), -1) {
+            names = append(names, xmlUnescape(xmlAttr(tag, "name")))
+        }
+        reportPrintf("  共享工作簿用户: %s", strings.Join(names, ", "))
+    }
+    for _, h := range findRelsByType(dir, wb, relRevisionHeaders) {
+        data, err := readPart(dir, h)
+        if err != nil {
+            continue
+        }
+        users := map[string]bool{}
+        var list []string
+        headers := revisionHeaderRe.FindAllString(string(data), -1)
+        for _, tag := range headers {
+            name := xmlUnescape(xmlAttr(tag, "userName"))
+            if !users[name] {
+                users[name] = true
+                list = append(list, name)
+            }
+        }
+        reportPrintf("  修订记录: %d 条, 修订人 %s", len(headers), strings.Join(list, ", "))
+    }
+}
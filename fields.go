@@ -0,0 +1,304 @@
+package main
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+)
+
+var (
+    fieldTokenRe   = regexp.MustCompile(`(?s)<w:fldChar\b[^>]*>|<w:instrText\b[^>]*>(.*?)</w:instrText>`)
+    fldSimpleRe    = regexp.MustCompile(`<w:fldSimple\b[^>]*>`)
+    headerFooterRe = regexp.MustCompile(`(?s)(<(?:\w+:)?(?:odd|even|first)(?:Header|Footer)>)(.*?)(</(?:\w+:)?(?:odd|even|first)(?:Header|Footer)>)`)
+)
+
+// 会泄露作者或路径的域，FILENAME/TEMPLATE 只在带 \p 开关时显示完整路径
+var pathFieldNames = map[string]bool{
+    "AUTHOR": true, "USERNAME": true, "USERINITIALS": true, "USERADDRESS": true,
+    "LASTSAVEDBY": true, "DOCPROPERTY": true,
+}
+
+type wordField struct {
+    instr    string
+    beginTag [2]int
+    begin    [2]int
+    sep      [2]int
+    end      [2]int
+}
+
+type textEdit struct {
+    start, end int
+    repl       string
+}
+
+// applyEdits 按位置应用替换，与前一处重叠的替换忽略
+func applyEdits(text string, edits []textEdit) string {
+    sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+    var b strings.Builder
+    pos := 0
+    for _, e := range edits {
+        if e.start < pos {
+            continue
+        }
+        b.WriteString(text[pos:e.start])
+        b.WriteString(e.repl)
+        pos = e.end
+    }
+    b.WriteString(text[pos:])
+    return b.String()
+}
+
+// isPathField 判断域代码是否会显示作者、用户名或文件路径
+func isPathField(instr string) bool {
+    words := strings.Fields(xmlUnescape(instr))
+    if len(words) == 0 {
+        return false
+    }
+    name := strings.ToUpper(words[0])
+    if pathFieldNames[name] {
+        return true
+    }
+    if name == "FILENAME" || name == "TEMPLATE" {
+        for _, w := range words[1:] {
+            if strings.EqualFold(w, `\p`) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// runSpan 返回 pos 所在的 w:r 元素区间
+func runSpan(text string, pos int) [2]int {
+    start := strings.LastIndex(text[:pos], "<w:r>")
+    if i := strings.LastIndex(text[:pos], "<w:r "); i > start {
+        start = i
+    }
+    end := strings.Index(text[pos:], "</w:r>")
+    if start < 0 || end < 0 {
+        return [2]int{pos, pos}
+    }
+    return [2]int{start, pos + end + len("</w:r>")}
+}
+
+// complexFields 解析 fldChar 形式的域(可嵌套)，按结束顺序返回
+func complexFields(text string) []wordField {
+    var fields, stack []wordField
+    for _, m := range fieldTokenRe.FindAllStringSubmatchIndex(text, -1) {
+        tag := text[m[0]:m[1]]
+        if m[2] >= 0 {
+            if n := len(stack); n > 0 && stack[n-1].sep[1] == 0 {
+                stack[n-1].instr += text[m[2]:m[3]]
+            }
+            continue
+        }
+        switch xmlAttr(tag, "w:fldCharType") {
+        case "begin":
+            stack = append(stack, wordField{beginTag: [2]int{m[0], m[1]}, begin: runSpan(text, m[0])})
+        case "separate":
+            if n := len(stack); n > 0 {
+                stack[n-1].sep = runSpan(text, m[0])
+            }
+        case "end":
+            if n := len(stack); n > 0 {
+                f := stack[n-1]
+                stack = stack[:n-1]
+                f.end = runSpan(text, m[0])
+                fields = append(fields, f)
+            }
+        }
+    }
+    return fields
+}
+
+// isNestedField 判断第 i 个域是否包含其他域或被其他域包含
+func isNestedField(fields []wordField, i int) bool {
+    f := fields[i]
+    for j, g := range fields {
+        if j != i && g.begin[0] < f.end[1] && f.begin[0] < g.end[1] {
+            return true
+        }
+    }
+    return false
+}
+
+// fieldResult 返回域结果中的文字
+func fieldResult(text string) string {
+    var b strings.Builder
+    for _, m := range wordTextRe.FindAllStringSubmatch(text, -1) {
+        b.WriteString(xmlUnescape(m[1]))
+    }
+    return b.String()
+}
+
+// neutralizeFields 处理一个部件中的路径/作者域
+// unlink: 去掉域代码，保留结果为普通文字
+// clear: 保留域代码，清空结果并标记为需要更新
+// remove: 删除整个域
+func neutralizeFields(text, policy string) (string, int) {
+    var edits []textEdit
+    count := 0
+    fields := complexFields(text)
+    for i, f := range fields {
+        if !isPathField(f.instr) {
+            continue
+        }
+        resStart := f.end[0]
+        if f.sep[1] > 0 {
+            resStart = f.sep[1]
+        }
+        // 跨段落或与其他域嵌套时，去掉域标记会破坏段落或外层域的结构
+        crossPara := strings.Contains(text[f.begin[0]:f.end[1]], "</w:p>") || isNestedField(fields, i)
+        switch policy {
+        case "unlink":
+            if crossPara {
+                continue
+            }
+            edits = append(edits, textEdit{f.begin[0], resStart, ""}, textEdit{f.end[0], f.end[1], ""})
+        case "clear":
+            if strings.Contains(text[resStart:f.end[0]], "</w:p>") {
+                continue
+            }
+            edits = append(edits, textEdit{f.beginTag[0], f.beginTag[1], setXMLAttr(text[f.beginTag[0]:f.beginTag[1]], "w:dirty", "true")})
+            edits = append(edits, textEdit{resStart, f.end[0], ""})
+        default:
+            if crossPara {
+                continue
+            }
+            edits = append(edits, textEdit{f.begin[0], f.end[1], ""})
+        }
+        count++
+    }
+
+    for _, s := range xmlElementSpans(text, "w:fldSimple") {
+        elem := text[s[0]:s[1]]
+        if !isPathField(xmlAttr(elem, "w:instr")) {
+            continue
+        }
+        open := fldSimpleRe.FindString(elem)
+        inner := ""
+        if !strings.HasSuffix(open, "/>") {
+            inner = elem[len(open) : len(elem)-len("</w:fldSimple>")]
+        }
+        switch policy {
+        case "unlink":
+            edits = append(edits, textEdit{s[0], s[1], inner})
+        case "clear":
+            tag := setXMLAttr(strings.TrimSuffix(strings.TrimSuffix(open, ">"), "/")+"/>", "w:dirty", "true")
+            edits = append(edits, textEdit{s[0], s[1], tag})
+        default:
+            edits = append(edits, textEdit{s[0], s[1], ""})
+        }
+        count++
+    }
+    return applyEdits(text, edits), count
+}
+
+// removePathCodes 删除页眉页脚中的 &Z(文件路径)和 &F(文件名)代码，&& 为转义的 & 字符
+func removePathCodes(s string) (string, bool) {
+    var b strings.Builder
+    found := false
+    for i := 0; i < len(s); i++ {
+        if s[i] == '&' && i+1 < len(s) {
+            switch s[i+1] {
+            case '&':
+                b.WriteString("&&")
+                i++
+                continue
+            case 'Z', 'F':
+                found = true
+                i++
+                continue
+            }
+        }
+        b.WriteByte(s[i])
+    }
+    return b.String(), found
+}
+
+// cleanPathFields 处理 Word 中显示路径、作者的域和 Excel 页眉页脚中的路径代码
+func cleanPathFields(dir, policy string) error {
+    for _, p := range wordStoryParts(dir) {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text, n := neutralizeFields(string(data), policy)
+        if n == 0 {
+            continue
+        }
+        logPrintf("处理路径/作者域: %s 共 %d 个", p, n)
+        if err := writePart(dir, p, []byte(text)); err != nil {
+            return err
+        }
+    }
+
+    for _, p := range listParts(dir) {
+        if !strings.HasPrefix(p, "xl/") || !strings.HasSuffix(p, ".xml") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        changed := false
+        text := headerFooterRe.ReplaceAllStringFunc(string(data), func(elem string) string {
+            m := headerFooterRe.FindStringSubmatch(elem)
+            s, found := removePathCodes(xmlUnescape(m[2]))
+            if !found {
+                return elem
+            }
+            changed = true
+            return m[1] + xmlEscape(s) + m[3]
+        })
+        if !changed {
+            continue
+        }
+        logPrintf("删除页眉页脚路径代码: %s", p)
+        if err := writePart(dir, p, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectPathFields(dir string) {
+    for _, p := range wordStoryParts(dir) {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        for _, f := range complexFields(text) {
+            if !isPathField(f.instr) {
+                continue
+            }
+            result := ""
+            if f.sep[1] > 0 && f.sep[1] <= f.end[0] {
+                result = fieldResult(text[f.sep[1]:f.end[0]])
+            }
+            reportPrintf("  路径/作者域: %s %s → %s", p, strings.TrimSpace(xmlUnescape(f.instr)), result)
+        }
+        for _, s := range xmlElementSpans(text, "w:fldSimple") {
+            elem := text[s[0]:s[1]]
+            if instr := xmlAttr(elem, "w:instr"); isPathField(instr) {
+                reportPrintf("  路径/作者域: %s %s → %s", p, strings.TrimSpace(xmlUnescape(instr)), fieldResult(elem))
+            }
+        }
+    }
+
+    for _, p := range listParts(dir) {
+        if !strings.HasPrefix(p, "xl/") || !strings.HasSuffix(p, ".xml") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        for _, m := range headerFooterRe.FindAllStringSubmatch(string(data), -1) {
+            if _, found := removePathCodes(xmlUnescape(m[2])); found {
+                reportPrintf("  页眉页脚路径代码(&Z/&F): %s %s", p, strings.Trim(m[1], "<>"))
+            }
+        }
+    }
+}
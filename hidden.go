@@ -0,0 +1,423 @@
+package main
+
+import (
+    "fmt"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+var (
+    wordStoryRe   = regexp.MustCompile(`^word/(document|header\d*|footer\d*|footnotes|endnotes|comments)\.xml$`)
+    vanishRe      = regexp.MustCompile(`<w:(?:vanish|specVanish)(?:\s+w:val="([^"]*)")?\s*/>`)
+    rStyleRe      = regexp.MustCompile(`<w:rStyle\s+w:val="([^"]*)"`)
+    pStyleRe      = regexp.MustCompile(`<w:pStyle\s+w:val="([^"]*)"`)
+    styleIDRe     = regexp.MustCompile(`<w:style\b[^>]*\sw:styleId="([^"]*)"`)
+    whiteColorRe  = regexp.MustCompile(`(?i)<w:color\s+w:val="FFFFFF"`)
+    wordTextRe    = regexp.MustCompile(`(?s)<w:(?:t|delText)(?:\s[^>]*)?>(.*?)</w:(?:t|delText)>`)
+    sldIDTagRe    = regexp.MustCompile(`<p:sldId\b[^>]*/>`)
+    sldTagRe      = regexp.MustCompile(`<p:sld\b[^>]*>`)
+    sldSzTagRe    = regexp.MustCompile(`<p:sldSz\b[^>]*>`)
+    custShowSldRe = regexp.MustCompile(`<p:sld\s+r:id="([^"]*)"\s*/>`)
+    cNvPrTagRe    = regexp.MustCompile(`<p:cNvPr\b[^>]*>`)
+    xfrmOffRe     = regexp.MustCompile(`<a:off\s+x="(-?\d+)"\s+y="(-?\d+)"\s*/>\s*<a:ext\s+cx="(\d+)"\s+cy="(\d+)"`)
+)
+
+var slideShapeNames = []string{"p:sp", "p:pic", "p:cxnSp", "p:graphicFrame", "p:grpSp"}
+
+// wordStoryParts 返回 Word 正文、页眉页脚、脚注尾注和批注部件
+func wordStoryParts(dir string) []string {
+    var parts []string
+    for _, p := range listParts(dir) {
+        if wordStoryRe.MatchString(p) {
+            parts = append(parts, p)
+        }
+    }
+    return parts
+}
+
+// vanishState 返回 rPr 中的隐藏设置: 1 隐藏，0 显式取消，-1 未设置
+func vanishState(rPr string) int {
+    m := vanishRe.FindStringSubmatch(rPr)
+    if m == nil {
+        return -1
+    }
+    switch m[1] {
+    case "0", "false", "off":
+        return 0
+    }
+    return 1
+}
+
+// hiddenWordStyles 返回 styles.xml 中设置了隐藏文字的样式
+func hiddenWordStyles(dir string) map[string]bool {
+    styles := map[string]bool{}
+    data, err := readPart(dir, "word/styles.xml")
+    if err != nil {
+        return styles
+    }
+    text := string(data)
+    for _, s := range xmlElementSpans(text, "w:style") {
+        elem := text[s[0]:s[1]]
+        if m := styleIDRe.FindStringSubmatch(elem); m != nil && vanishState(elem) == 1 {
+            styles[m[1]] = true
+        }
+    }
+    return styles
+}
+
+// runProps 返回元素开头紧跟的属性元素，如 <w:r> 的 <w:rPr>
+func runProps(elem, start, prName string) string {
+    body := elem[len(start):]
+    if !strings.HasPrefix(body, "<"+prName) {
+        return ""
+    }
+    if end := strings.Index(body, "</"+prName+">"); end >= 0 {
+        return body[:end]
+    }
+    return body[:strings.Index(body, ">")+1]
+}
+
+type wordRun struct {
+    span   [2]int
+    hidden bool
+    white  bool
+    text   string
+}
+
+// wordRuns 分析部件中的每个 <w:r>，判断是否为隐藏文字
+func wordRuns(text string, hiddenStyles map[string]bool) []wordRun {
+    type para struct {
+        span   [2]int
+        hidden bool
+    }
+    var paras []para
+    for _, s := range xmlElementSpans(text, "w:p") {
+        elem := text[s[0]:s[1]]
+        start := elem[:strings.Index(elem, ">")+1]
+        pPr := runProps(elem, start, "w:pPr")
+        hidden := false
+        if m := pStyleRe.FindStringSubmatch(pPr); m != nil {
+            hidden = hiddenStyles[m[1]]
+        }
+        paras = append(paras, para{s, hidden})
+    }
+
+    var runs []wordRun
+    for _, s := range xmlElementSpans(text, "w:r") {
+        elem := text[s[0]:s[1]]
+        start := elem[:strings.Index(elem, ">")+1]
+        rPr := runProps(elem, start, "w:rPr")
+        state := vanishState(rPr)
+        if state < 0 {
+            if m := rStyleRe.FindStringSubmatch(rPr); m != nil && hiddenStyles[m[1]] {
+                state = 1
+            }
+        }
+        if state < 0 {
+            // 取最内层段落的样式
+            for i := len(paras) - 1; i >= 0; i-- {
+                if paras[i].span[0] <= s[0] && s[1] <= paras[i].span[1] {
+                    if paras[i].hidden {
+                        state = 1
+                    }
+                    break
+                }
+            }
+        }
+        var words []string
+        for _, m := range wordTextRe.FindAllStringSubmatch(elem, -1) {
+            words = append(words, xmlUnescape(m[1]))
+        }
+        runs = append(runs, wordRun{
+            span:   s,
+            hidden: state == 1,
+            white:  whiteColorRe.MatchString(rPr),
+            text:   strings.Join(words, ""),
+        })
+    }
+    return runs
+}
+
+// cleanHiddenText 删除 Word 中的隐藏文字，unhide 策略改为取消隐藏(包括样式中的隐藏设置)
+func cleanHiddenText(dir, policy string) error {
+    if !strings.HasPrefix(mainDocumentPart(dir), "word/") {
+        return nil
+    }
+    hiddenStyles := hiddenWordStyles(dir)
+    if policy == "unhide" {
+        if data, err := readPart(dir, "word/styles.xml"); err == nil && vanishRe.Match(data) {
+            if err := writePart(dir, "word/styles.xml", vanishRe.ReplaceAll(data, nil)); err != nil {
+                return err
+            }
+            logPrintf("取消样式中的隐藏文字: word/styles.xml")
+        }
+    }
+    for _, p := range wordStoryParts(dir) {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        var spans [][2]int
+        for _, r := range wordRuns(text, hiddenStyles) {
+            if !r.hidden {
+                continue
+            }
+            // 含域标记的运行删除后会破坏域结构，保留
+            if strings.Contains(text[r.span[0]:r.span[1]], "<w:fldChar") {
+                continue
+            }
+            spans = append(spans, r.span)
+        }
+        if len(spans) == 0 {
+            continue
+        }
+        if policy == "unhide" {
+            text = vanishRe.ReplaceAllString(text, "")
+            logPrintf("取消隐藏文字: %s", p)
+        } else {
+            text = removeSpans(text, spans)
+            logPrintf("删除隐藏文字: %s, %d 处", p, len(spans))
+        }
+        if err := writePart(dir, p, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+type slideInfo struct {
+    Part   string
+    ID     string
+    RelID  string
+    Hidden bool
+    tag    string
+}
+
+// presentationSlides 按 presentation.xml 中的顺序返回幻灯片
+func presentationSlides(dir string) []slideInfo {
+    pres := mainDocumentPart(dir)
+    if !strings.HasPrefix(pres, "ppt/") {
+        return nil
+    }
+    data, err := readPart(dir, pres)
+    if err != nil {
+        return nil
+    }
+    targets := map[string]string{}
+    for _, r := range readRels(dir, relsPartFor(pres)) {
+        targets[r.ID] = resolveTarget(pres, r.Target)
+    }
+    var slides []slideInfo
+    for _, tag := range sldIDTagRe.FindAllString(string(data), -1) {
+        s := slideInfo{
+            Part:  targets[xmlAttr(tag, "r:id")],
+            ID:    xmlAttr(tag, "id"),
+            RelID: xmlAttr(tag, "r:id"),
+            tag:   tag,
+        }
+        if slide, err := readPart(dir, s.Part); err == nil {
+            show := xmlAttr(sldTagRe.FindString(string(slide)), "show")
+            s.Hidden = show == "0" || show == "false"
+        }
+        slides = append(slides, s)
+    }
+    return slides
+}
+
+// hiddenShapeSpans 返回幻灯片中 cNvPr 标记为隐藏的形状
+func hiddenShapeSpans(text string) [][2]int {
+    var spans [][2]int
+    for _, name := range slideShapeNames {
+        for _, s := range xmlElementSpans(text, name) {
+            tag := cNvPrTagRe.FindString(text[s[0]:s[1]])
+            if h := xmlAttr(tag, "hidden"); h == "1" || h == "true" {
+                spans = append(spans, s)
+            }
+        }
+    }
+    sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+    return spans
+}
+
+// cleanHiddenSlides 删除隐藏幻灯片和隐藏形状，unhide 策略改为取消隐藏
+func cleanHiddenSlides(dir, policy string) error {
+    pres := mainDocumentPart(dir)
+    slides := presentationSlides(dir)
+    if len(slides) == 0 {
+        return nil
+    }
+
+    visible := 0
+    for _, s := range slides {
+        if !s.Hidden {
+            visible++
+        }
+    }
+    data, err := readPart(dir, pres)
+    if err != nil {
+        return err
+    }
+    text := string(data)
+    for i, s := range slides {
+        if !s.Hidden || s.Part == "" {
+            continue
+        }
+        if policy == "unhide" {
+            slide, err := readPart(dir, s.Part)
+            if err != nil {
+                continue
+            }
+            fixed := sldTagRe.ReplaceAllStringFunc(string(slide), func(tag string) string {
+                return removeXMLAttr(tag, "show")
+            })
+            if err := writePart(dir, s.Part, []byte(fixed)); err != nil {
+                return err
+            }
+            logPrintf("取消隐藏幻灯片: 第 %d 张", i+1)
+            continue
+        }
+        if visible == 0 {
+            logPrintf("演示文稿没有可见幻灯片，跳过删除隐藏幻灯片")
+            break
+        }
+        logPrintf("删除隐藏幻灯片: 第 %d 张 %s", i+1, s.Part)
+        text = strings.Replace(text, s.tag, "", 1)
+        text = strings.Replace(text, `<p14:sldId id="`+s.ID+`"/>`, "", -1)
+        if err := removePartTree(dir, s.Part); err != nil {
+            return err
+        }
+    }
+
+    // 自定义放映中指向已删除幻灯片的条目
+    ids := map[string]bool{}
+    for _, r := range readRels(dir, relsPartFor(pres)) {
+        ids[r.ID] = true
+    }
+    text = custShowSldRe.ReplaceAllStringFunc(text, func(tag string) string {
+        if ids[custShowSldRe.FindStringSubmatch(tag)[1]] {
+            return tag
+        }
+        return ""
+    })
+    if err := writePart(dir, pres, []byte(text)); err != nil {
+        return err
+    }
+
+    for _, s := range presentationSlides(dir) {
+        slide, err := readPart(dir, s.Part)
+        if err != nil {
+            continue
+        }
+        spans := hiddenShapeSpans(string(slide))
+        if len(spans) == 0 {
+            continue
+        }
+        var fixed string
+        if policy == "unhide" {
+            fixed = cNvPrTagRe.ReplaceAllStringFunc(string(slide), func(tag string) string {
+                return removeXMLAttr(tag, "hidden")
+            })
+            logPrintf("取消隐藏形状: %s", s.Part)
+        } else {
+            fixed = removeSpans(string(slide), spans)
+            logPrintf("删除隐藏形状: %s, %d 个", s.Part, len(spans))
+        }
+        if err := writePart(dir, s.Part, []byte(fixed)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// cleanHiddenContent 按文档类型处理隐藏内容
+func cleanHiddenContent(dir, policy string) error {
+    if err := cleanHiddenSheets(dir, policy); err != nil {
+        return err
+    }
+    if err := cleanHiddenSlides(dir, policy); err != nil {
+        return err
+    }
+    return cleanHiddenText(dir, policy)
+}
+
+func excerpt(s string, n int) string {
+    r := []rune(strings.TrimSpace(s))
+    if len(r) > n {
+        return string(r[:n]) + "..."
+    }
+    return string(r)
+}
+
+func inspectHiddenContent(dir string) {
+    inspectHiddenSheets(dir)
+
+    if strings.HasPrefix(mainDocumentPart(dir), "word/") {
+        hiddenStyles := hiddenWordStyles(dir)
+        for _, p := range wordStoryParts(dir) {
+            data, err := readPart(dir, p)
+            if err != nil {
+                continue
+            }
+            var hidden, white []string
+            for _, r := range wordRuns(string(data), hiddenStyles) {
+                if r.text == "" {
+                    continue
+                }
+                if r.hidden {
+                    hidden = append(hidden, r.text)
+                } else if r.white {
+                    white = append(white, r.text)
+                }
+            }
+            if len(hidden) > 0 {
+                reportPrintf("  隐藏文字: %s 共 %d 处: %s", p, len(hidden), excerpt(strings.Join(hidden, " "), 60))
+            }
+            if len(white) > 0 {
+                reportPrintf("  白色文字(疑似隐藏): %s 共 %d 处: %s", p, len(white), excerpt(strings.Join(white, " "), 60))
+            }
+        }
+    }
+
+    slides := presentationSlides(dir)
+    if len(slides) == 0 {
+        return
+    }
+    var cx, cy int64
+    if data, err := readPart(dir, mainDocumentPart(dir)); err == nil {
+        tag := sldSzTagRe.FindString(string(data))
+        fmt.Sscanf(xmlAttr(tag, "cx"), "%d", &cx)
+        fmt.Sscanf(xmlAttr(tag, "cy"), "%d", &cy)
+    }
+    for i, s := range slides {
+        if s.Hidden {
+            reportPrintf("  隐藏幻灯片: 第 %d 张 %s", i+1, s.Part)
+        }
+        data, err := readPart(dir, s.Part)
+        if err != nil {
+            continue
+        }
+        if n := len(hiddenShapeSpans(string(data))); n > 0 {
+            reportPrintf("  隐藏形状: 第 %d 张 %s 共 %d 个", i+1, path.Base(s.Part), n)
+        }
+        if cx == 0 || cy == 0 {
+            continue
+        }
+        off := 0
+        for _, m := range xfrmOffRe.FindAllStringSubmatch(string(data), -1) {
+            var x, y, w, h int64
+            fmt.Sscanf(m[1], "%d", &x)
+            fmt.Sscanf(m[2], "%d", &y)
+            fmt.Sscanf(m[3], "%d", &w)
+            fmt.Sscanf(m[4], "%d", &h)
+            if x >= cx || y >= cy || x+w <= 0 || y+h <= 0 {
+                off++
+            }
+        }
+        if off > 0 {
+            reportPrintf("  幻灯片外形状: 第 %d 张 %s 共 %d 个", i+1, path.Base(s.Part), off)
+        }
+    }
+}
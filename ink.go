@@ -0,0 +1,166 @@
+package main
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+)
+
+const inkContentType = "application/inkml+xml"
+
+var (
+    inkPartRe       = regexp.MustCompile(`^(?:word|xl|ppt)/ink/[^/]+\.xml$`)
+    contentPartRe   = regexp.MustCompile(`<(\w+):contentPart\b[^>]*>`)
+    inkTimestampRe  = regexp.MustCompile(`<inkml:timestamp\b[^>]*>`)
+    inkSourceRe     = regexp.MustCompile(`<inkml:inkSource\b[^>]*>`)
+    inkTimeStringRe = regexp.MustCompile(`\stimeString="([^"]*)"`)
+    inkSourceAttrs  = []string{"manufacturer", "model", "serialNo", "specificationRef", "description"}
+    inkAnchorNames  = []string{"xdr:twoCellAnchor", "xdr:oneCellAnchor", "xdr:absoluteAnchor", "mc:AlternateContent", "w:drawing"}
+)
+
+// inkParts 返回包内的 InkML 墨迹部件
+func inkParts(dir string) []string {
+    var parts []string
+    for _, p := range listParts(dir) {
+        if inkPartRe.MatchString(p) || (strings.HasSuffix(p, ".xml") && contentTypeOf(dir, p) == inkContentType) {
+            parts = append(parts, p)
+        }
+    }
+    return parts
+}
+
+// enclosingSpan 返回包含 [start,end) 的最内层 qname 元素区间
+func enclosingSpan(text, qname string, start, end int) ([2]int, bool) {
+    var found [2]int
+    ok := false
+    for _, s := range xmlElementSpans(text, qname) {
+        if s[0] <= start && s[1] >= end && (!ok || s[0] >= found[0]) {
+            found, ok = s, true
+        }
+    }
+    return found, ok
+}
+
+// removeInkAnchors 删除引用墨迹部件的 contentPart 元素，连同其所在的绘图锚点或兼容性分支，
+// 同时返回被删除内容中引用的关系 ID
+func removeInkAnchors(text string, ids map[string]bool) (string, []string) {
+    var spans [][2]int
+    for _, m := range contentPartRe.FindAllStringSubmatchIndex(text, -1) {
+        tag := text[m[0]:m[1]]
+        if !ids[xmlAttr(tag, "r:id")] {
+            continue
+        }
+        qname := text[m[2]:m[3]] + ":contentPart"
+        span := [2]int{m[0], m[1]}
+        if !strings.HasSuffix(tag, "/>") {
+            if s, ok := enclosingSpan(text, qname, m[0], m[1]); ok {
+                span = s
+            }
+        }
+        for _, name := range inkAnchorNames {
+            if s, ok := enclosingSpan(text, name, span[0], span[1]); ok {
+                span = s
+            }
+        }
+        spans = append(spans, span)
+    }
+    sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+    var refs []string
+    for _, s := range spans {
+        for _, m := range relIDRe.FindAllStringSubmatch(text[s[0]:s[1]], -1) {
+            refs = append(refs, m[1])
+        }
+    }
+    return removeSpans(text, spans), refs
+}
+
+// cleanInk 处理墨迹注释
+// remove: 删除墨迹部件及其在文档中的锚点
+// strip: 保留笔迹，删除时间戳、输入设备信息和识别结果
+func cleanInk(dir, policy string) error {
+    parts := inkParts(dir)
+    if len(parts) == 0 {
+        return nil
+    }
+
+    if policy == "strip" {
+        for _, p := range parts {
+            data, err := readPart(dir, p)
+            if err != nil {
+                continue
+            }
+            text := inkTimestampRe.ReplaceAllString(string(data), "")
+            text = removeXMLAttr(text, "timestampRef")
+            text = inkSourceRe.ReplaceAllStringFunc(text, func(tag string) string {
+                for _, a := range inkSourceAttrs {
+                    tag = removeXMLAttr(tag, a)
+                }
+                return tag
+            })
+            text = removeSpans(text, xmlElementSpans(text, "inkml:annotationXML"))
+            text = removeSpans(text, xmlElementSpans(text, "inkml:annotation"))
+            logPrintf("清除墨迹元数据: %s", p)
+            if err := writePart(dir, p, []byte(text)); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    isInk := map[string]bool{}
+    for _, p := range parts {
+        isInk[strings.ToLower(p)] = true
+    }
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        ids := map[string]bool{}
+        for _, r := range readRels(dir, rels) {
+            if !r.external() && isInk[strings.ToLower(resolveTarget(source, r.Target))] {
+                ids[r.ID] = true
+            }
+        }
+        if len(ids) == 0 {
+            continue
+        }
+        data, err := readPart(dir, source)
+        if err != nil {
+            continue
+        }
+        text, refs := removeInkAnchors(string(data), ids)
+        if err := writePart(dir, source, []byte(text)); err != nil {
+            return err
+        }
+        if err := removeDroppedRels(dir, source, text, refs); err != nil {
+            return err
+        }
+    }
+    for _, p := range parts {
+        logPrintf("删除墨迹: %s", p)
+        if err := removePartTree(dir, p); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectInk(dir string) {
+    for _, p := range inkParts(dir) {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        var info []string
+        if m := inkTimeStringRe.FindStringSubmatch(text); m != nil {
+            info = append(info, "时间 "+m[1])
+        }
+        src := inkSourceRe.FindString(text)
+        for _, a := range inkSourceAttrs[:3] {
+            if v := xmlAttr(src, a); v != "" {
+                info = append(info, a+" "+xmlUnescape(v))
+            }
+        }
+        reportPrintf("  墨迹: %s %s", p, strings.Join(info, ", "))
+    }
+}
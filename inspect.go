@@ -0,0 +1,55 @@
+package main
+
+import (
+    "fmt"
+    "os"
+)
+
+// inspectFile 解压到临时目录后逐项检查，结果输出到标准输出和日志，不修改原文件
+func inspectFile(filePath string) error {
+    reportPrintf("文件: %s", filePath)
+    if isCFBFile(filePath) {
+        return inspectLegacyFile(filePath)
+    }
+    if !isZipFile(filePath) {
+        reportPrintf("  非OOXML格式，跳过检查")
+        return nil
+    }
+
+    dir, err := os.MkdirTemp("", "cleanmeta")
+    if err != nil {
+        return err
+    }
+    defer os.RemoveAll(dir)
+
+    err = extractPackage(filePath, dir, nil)
+    if err != nil {
+        return err
+    }
+
+    if isODFPackage(dir) {
+        inspectODF(dir)
+        return nil
+    }
+
+    inspectSignatures(dir)
+    inspectConnections(dir)
+    inspectPivotCaches(dir)
+    inspectHiddenContent(dir)
+    inspectRevisions(dir)
+    inspectProtection(dir)
+    inspectPermissions(dir)
+    inspectAddins(dir)
+    inspectInk(dir)
+    inspectAltText(dir)
+    inspectPathFields(dir)
+    inspectExternalLinks(dir)
+    inspectOrphanParts(dir)
+    return nil
+}
+
+func reportPrintf(format string, args ...interface{}) {
+    msg := fmt.Sprintf(format, args...)
+    fmt.Println(msg)
+    logPrintf("%s", msg)
+}
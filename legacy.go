@@ -0,0 +1,233 @@
+package main
+
+import (
+    "encoding/binary"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+    "unicode/utf16"
+    "unicode/utf8"
+)
+
+const (
+    summaryInfoStream    = "\x05SummaryInformation"
+    docSummaryInfoStream = "\x05DocumentSummaryInformation"
+
+    vtI2       = 0x0002
+    vtLPSTR    = 0x001E
+    vtLPWSTR   = 0x001F
+    vtFILETIME = 0x0040
+)
+
+// 属性集中常见的属性名(PIDSI/PIDDSI)
+var summaryPropNames = map[uint32]string{
+    2: "标题", 3: "主题", 4: "作者", 5: "关键词", 6: "备注", 7: "模板", 8: "最后保存者",
+    11: "最后打印", 12: "创建时间", 13: "最后保存时间", 18: "应用程序",
+}
+
+var docSummaryPropNames = map[uint32]string{
+    2: "类别", 14: "经理", 15: "单位",
+}
+
+func isCFBFile(path string) bool {
+    f, err := os.Open(path)
+    if err != nil {
+        return false
+    }
+    defer f.Close()
+    header := make([]byte, 512)
+    if _, err := f.Read(header); err != nil {
+        return false
+    }
+    return isCFB(header)
+}
+
+// blankPropertySet 清空属性集：只保留第一节的代码页，删除其余属性和用户自定义属性节，
+// 长度不变，多出的部分补零
+func blankPropertySet(data []byte) ([]byte, error) {
+    if len(data) < 56 || binary.LittleEndian.Uint16(data) != 0xFFFE {
+        return nil, fmt.Errorf("属性集格式无效")
+    }
+    secOff := int(binary.LittleEndian.Uint32(data[44:]))
+    if secOff+8 > len(data) {
+        return nil, fmt.Errorf("属性集格式无效")
+    }
+
+    codepage := -1
+    n := int(binary.LittleEndian.Uint32(data[secOff+4:]))
+    for i := 0; i < n && secOff+16+i*8 <= len(data); i++ {
+        pid := binary.LittleEndian.Uint32(data[secOff+8+i*8:])
+        p := secOff + int(binary.LittleEndian.Uint32(data[secOff+12+i*8:]))
+        if pid == 1 && p+6 <= len(data) && binary.LittleEndian.Uint16(data[p:]) == vtI2 {
+            codepage = int(binary.LittleEndian.Uint16(data[p+4:]))
+        }
+    }
+
+    out := make([]byte, len(data))
+    copy(out, data[:44])
+    binary.LittleEndian.PutUint32(out[24:], 1)
+    binary.LittleEndian.PutUint32(out[44:], 48)
+    sec := out[48:]
+    if codepage >= 0 && len(sec) >= 24 {
+        binary.LittleEndian.PutUint32(sec[0:], 24)
+        binary.LittleEndian.PutUint32(sec[4:], 1)
+        binary.LittleEndian.PutUint32(sec[8:], 1)
+        binary.LittleEndian.PutUint32(sec[12:], 16)
+        binary.LittleEndian.PutUint16(sec[16:], vtI2)
+        binary.LittleEndian.PutUint16(sec[20:], uint16(codepage))
+    } else {
+        binary.LittleEndian.PutUint32(sec[0:], 8)
+    }
+    return out, nil
+}
+
+// removeLegacyProperties 直接修改旧版二进制文档(.doc/.xls/.ppt 等)，
+// 清空摘要信息属性集和目录项时间，保持原格式
+func removeLegacyProperties(filePath string) error {
+    data, err := os.ReadFile(filePath)
+    if err != nil {
+        return err
+    }
+    f, err := readCFB(data)
+    if err != nil {
+        return err
+    }
+
+    for _, name := range []string{summaryInfoStream, docSummaryInfoStream} {
+        e := f.Root.find(name)
+        if e == nil || len(e.Data) == 0 {
+            continue
+        }
+        blank, err := blankPropertySet(e.Data)
+        if err != nil {
+            logPrintf("跳过无法解析的属性集: %s %s", filePath, strings.TrimPrefix(name, "\x05"))
+            continue
+        }
+        if err := f.writeStream(e, blank); err != nil {
+            return err
+        }
+        logPrintf("清空属性集: %s", strings.TrimPrefix(name, "\x05"))
+    }
+    if f.Root.find("WordDocument") != nil {
+        if err := cleanWordBinary(f); err != nil {
+            return err
+        }
+    }
+    resized, err := cleanExcelBinary(f)
+    if err != nil {
+        return err
+    }
+    pptResized, err := cleanPowerPointBinary(f)
+    if err != nil {
+        return err
+    }
+    resized = resized || pptResized
+    if err := f.clearTimes(); err != nil {
+        return err
+    }
+
+    // 流长度改变时无法原地修改，重建整个复合文档
+    out := f.raw
+    if resized || rebuildCFB {
+        if sectors, mini := f.slackSectors(); sectors+mini > 0 {
+            logPrintf("清零空闲扇区: %d 个扇区, %d 个迷你流块", sectors, mini)
+        }
+        out = f.bytes()
+    }
+    if err := os.WriteFile(filePath+".tmp", out, 0644); err != nil {
+        os.Remove(filePath + ".tmp")
+        return err
+    }
+    return os.Rename(filePath+".tmp", filePath)
+}
+
+// propertyValues 读取属性集第一节中的字符串和时间属性
+func propertyValues(data []byte, names map[uint32]string) []string {
+    if len(data) < 48 || binary.LittleEndian.Uint16(data) != 0xFFFE {
+        return nil
+    }
+    secOff := int(binary.LittleEndian.Uint32(data[44:]))
+    if secOff+8 > len(data) {
+        return nil
+    }
+    var values []string
+    n := int(binary.LittleEndian.Uint32(data[secOff+4:]))
+    for i := 0; i < n && secOff+16+i*8 <= len(data); i++ {
+        pid := binary.LittleEndian.Uint32(data[secOff+8+i*8:])
+        p := secOff + int(binary.LittleEndian.Uint32(data[secOff+12+i*8:]))
+        name, ok := names[pid]
+        if !ok || p+8 > len(data) {
+            continue
+        }
+        var v string
+        switch binary.LittleEndian.Uint16(data[p:]) {
+        case vtLPSTR:
+            size := int(binary.LittleEndian.Uint32(data[p+4:]))
+            if p+8+size > len(data) {
+                continue
+            }
+            raw := strings.TrimRight(string(data[p+8:p+8+size]), "\x00")
+            if !utf8.ValidString(raw) {
+                raw = fmt.Sprintf("<%d 字节，非 UTF-8 编码>", len(raw))
+            }
+            v = raw
+        case vtLPWSTR:
+            count := int(binary.LittleEndian.Uint32(data[p+4:]))
+            if p+8+count*2 > len(data) {
+                continue
+            }
+            units := make([]uint16, count)
+            for j := range units {
+                units[j] = binary.LittleEndian.Uint16(data[p+8+j*2:])
+            }
+            v = strings.TrimRight(string(utf16.Decode(units)), "\x00")
+        case vtFILETIME:
+            if p+12 > len(data) {
+                continue
+            }
+            ft := int64(binary.LittleEndian.Uint64(data[p+4:]))
+            if ft == 0 {
+                continue
+            }
+            v = time.Unix(0, (ft-116444736000000000)*100).UTC().Format("2006-01-02 15:04:05")
+        default:
+            continue
+        }
+        if v != "" {
+            values = append(values, name+": "+v)
+        }
+    }
+    if binary.LittleEndian.Uint32(data[24:]) > 1 {
+        values = append(values, "用户自定义属性")
+    }
+    return values
+}
+
+func inspectLegacyFile(filePath string) error {
+    data, err := os.ReadFile(filePath)
+    if err != nil {
+        return err
+    }
+    f, err := readCFB(data)
+    if err != nil {
+        return err
+    }
+    for _, s := range []struct {
+        stream string
+        names  map[uint32]string
+    }{{summaryInfoStream, summaryPropNames}, {docSummaryInfoStream, docSummaryPropNames}} {
+        if e := f.Root.find(s.stream); e != nil {
+            for _, v := range propertyValues(e.Data, s.names) {
+                reportPrintf("  摘要信息: %s", v)
+            }
+        }
+    }
+    inspectWordBinary(f)
+    inspectExcelBinary(f)
+    inspectPowerPointBinary(f)
+    if sectors, mini := f.slackSectors(); sectors+mini > 0 {
+        reportPrintf("  空闲扇区残留数据: %d 个扇区, %d 个迷你流块", sectors, mini)
+    }
+    return nil
+}
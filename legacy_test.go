@@ -0,0 +1,106 @@
+package main
+
+import (
+    "encoding/binary"
+    "testing"
+)
+
+type testProp struct {
+    id   uint32
+    data []byte
+}
+
+func lpstrProp(id uint32, s string) testProp {
+    v := binary.LittleEndian.AppendUint32(nil, vtLPSTR)
+    v = binary.LittleEndian.AppendUint32(v, uint32(len(s)+1))
+    v = append(v, s...)
+    v = append(v, 0)
+    for len(v)%4 != 0 {
+        v = append(v, 0)
+    }
+    return testProp{id, v}
+}
+
+func propSection(props []testProp) []byte {
+    head := 8 + len(props)*8
+    var values []byte
+    sec := make([]byte, 8, head)
+    binary.LittleEndian.PutUint32(sec[4:], uint32(len(props)))
+    for _, p := range props {
+        sec = binary.LittleEndian.AppendUint32(sec, p.id)
+        sec = binary.LittleEndian.AppendUint32(sec, uint32(head+len(values)))
+        values = append(values, p.data...)
+    }
+    sec = append(sec, values...)
+    binary.LittleEndian.PutUint32(sec, uint32(len(sec)))
+    return sec
+}
+
+// testPropertySet 生成属性集流，每节使用不同的 FMTID
+func testPropertySet(sections ...[]testProp) []byte {
+    b := make([]byte, 24)
+    binary.LittleEndian.PutUint16(b, 0xFFFE)
+    b = binary.LittleEndian.AppendUint32(b, uint32(len(sections)))
+    offset := len(b) + len(sections)*20
+    var body []byte
+    for i, props := range sections {
+        fmtid := make([]byte, 16)
+        fmtid[0] = byte(i + 1)
+        b = append(b, fmtid...)
+        b = binary.LittleEndian.AppendUint32(b, uint32(offset+len(body)))
+        body = append(body, propSection(props)...)
+    }
+    return append(b, body...)
+}
+
+func TestBlankPropertySet(t *testing.T) {
+    codepage := testProp{1, []byte{vtI2, 0, 0, 0, 0xE4, 0x04, 0, 0}}
+    created := testProp{12, []byte{vtFILETIME, 0, 0, 0, 0, 0x40, 0x8A, 0xF3, 0x2C, 0x83, 0xD8, 0x01}}
+
+    cases := []struct {
+        name     string
+        data     []byte
+        codepage int
+    }{
+        {"含代码页", testPropertySet([]testProp{codepage, lpstrProp(4, "Zhang San"), created, lpstrProp(8, "Li Si")}), 1252},
+        {"无代码页", testPropertySet([]testProp{lpstrProp(4, "Zhang San")}), -1},
+        {"自定义属性节", testPropertySet([]testProp{codepage, lpstrProp(15, "ACME Corp")}, []testProp{lpstrProp(2, "secret")}), 1252},
+    }
+    for _, c := range cases {
+        if len(propertyValues(c.data, summaryPropNames))+len(propertyValues(c.data, docSummaryPropNames)) == 0 {
+            t.Fatalf("%s: 测试数据中没有属性", c.name)
+        }
+        out, err := blankPropertySet(c.data)
+        if err != nil {
+            t.Errorf("%s: %v", c.name, err)
+            continue
+        }
+        if len(out) != len(c.data) {
+            t.Errorf("%s: 长度 %d，原始 %d", c.name, len(out), len(c.data))
+        }
+        if n := binary.LittleEndian.Uint32(out[24:]); n != 1 {
+            t.Errorf("%s: 剩余 %d 节", c.name, n)
+        }
+        if v := propertyValues(out, summaryPropNames); len(v) > 0 {
+            t.Errorf("%s: 仍有属性 %v", c.name, v)
+        }
+        if v := propertyValues(out, docSummaryPropNames); len(v) > 0 {
+            t.Errorf("%s: 仍有属性 %v", c.name, v)
+        }
+
+        sec := out[binary.LittleEndian.Uint32(out[44:]):]
+        count := int(binary.LittleEndian.Uint32(sec[4:]))
+        switch {
+        case c.codepage < 0 && count != 0:
+            t.Errorf("%s: 剩余 %d 个属性", c.name, count)
+        case c.codepage >= 0 && (count != 1 || binary.LittleEndian.Uint32(sec[8:]) != 1):
+            t.Errorf("%s: 没有只保留代码页", c.name)
+        case c.codepage >= 0 && int(binary.LittleEndian.Uint16(sec[20:])) != c.codepage:
+            t.Errorf("%s: 代码页 %d，原始 %d", c.name, binary.LittleEndian.Uint16(sec[20:]), c.codepage)
+        }
+    }
+
+    if _, err := blankPropertySet([]byte{0xFE, 0xFF}); err == nil {
+        t.Error("格式无效的属性集应返回错误")
+    }
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path"
+    "regexp"
+    "strings"
+)
+
+// 引用外部关系的元素，删除链接时整个删除；w:hyperlink 只去掉外壳保留文字
+var linkElementNames = []string{"a:hlinkClick", "a:hlinkHover", "a:hlinkMouseOver", "hyperlink", "c:externalData", "w:attachedTemplate", "o:OLEObject"}
+
+var (
+    blipTagRe       = regexp.MustCompile(`<a:blip\b[^>]*>`)
+    imagedataTagRe  = regexp.MustCompile(`<v:imagedata\b[^>]*>`)
+    emptyHyperlinks = regexp.MustCompile(`<hyperlinks\s*(?:/>|>\s*</hyperlinks>)`)
+)
+
+type linkRule struct {
+    re   *regexp.Regexp
+    repl string
+}
+
+var linkRules []linkRule
+
+// loadLinkRules 读取链接改写规则，每行 "正则 => 替换"，# 开头为注释
+func loadLinkRules(file string) ([]linkRule, error) {
+    data, err := os.ReadFile(file)
+    if err != nil {
+        return nil, err
+    }
+    var rules []linkRule
+    for i, line := range strings.Split(string(data), "\n") {
+        line = strings.TrimSpace(line)
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        parts := strings.SplitN(line, "=>", 2)
+        if len(parts) != 2 {
+            return nil, fmt.Errorf("第 %d 行缺少 =>", i+1)
+        }
+        re, err := regexp.Compile(strings.TrimSpace(parts[0]))
+        if err != nil {
+            return nil, fmt.Errorf("第 %d 行: %v", i+1, err)
+        }
+        rules = append(rules, linkRule{re, strings.TrimSpace(parts[1])})
+    }
+    return rules, nil
+}
+
+// setRelTargets 改写关系目标，fn 返回新的目标(未转义)，返回空串表示不修改
+func setRelTargets(dir, rels string, fn func(relationship) string) error {
+    data, err := readPart(dir, rels)
+    if err != nil {
+        return nil
+    }
+    text := string(data)
+    changed := false
+    for _, r := range readRels(dir, rels) {
+        if t := fn(r); t != "" && t != xmlUnescape(r.Target) {
+            text = strings.Replace(text, r.raw, setXMLAttr(r.raw, "Target", xmlEscape(t)), 1)
+            changed = true
+        }
+    }
+    if !changed {
+        return nil
+    }
+    return writePart(dir, rels, []byte(text))
+}
+
+// unlinkPictures 去掉同时有嵌入副本的图片上的外部链接，以及 VML 图片的原始路径
+func unlinkPictures(text string, ids map[string]bool) string {
+    text = blipTagRe.ReplaceAllStringFunc(text, func(tag string) string {
+        if ids[xmlAttr(tag, "r:link")] && xmlAttr(tag, "r:embed") != "" {
+            return removeXMLAttr(tag, "r:link")
+        }
+        return tag
+    })
+    return imagedataTagRe.ReplaceAllStringFunc(text, func(tag string) string {
+        return removeXMLAttr(tag, "o:href")
+    })
+}
+
+// removeLinkRefs 删除部件中引用 ids 外部关系的超链接、外部数据和链接对象
+func removeLinkRefs(text string, ids map[string]bool) string {
+    var edits []textEdit
+    for _, s := range xmlElementSpans(text, "w:hyperlink") {
+        elem := text[s[0]:s[1]]
+        open := elem[:strings.Index(elem, ">")+1]
+        if !ids[xmlAttr(open, "r:id")] {
+            continue
+        }
+        inner := ""
+        if !strings.HasSuffix(open, "/>") {
+            inner = elem[len(open) : len(elem)-len("</w:hyperlink>")]
+        }
+        edits = append(edits, textEdit{s[0], s[1], inner})
+    }
+    for _, name := range linkElementNames {
+        for _, s := range xmlElementSpans(text, name) {
+            elem := text[s[0]:s[1]]
+            if ids[xmlAttr(elem[:strings.Index(elem, ">")+1], "r:id")] {
+                edits = append(edits, textEdit{s[0], s[1], ""})
+            }
+        }
+    }
+    text = applyEdits(text, edits)
+    text = emptyHyperlinks.ReplaceAllString(text, "")
+    return blipTagRe.ReplaceAllStringFunc(unlinkPictures(text, ids), func(tag string) string {
+        if ids[xmlAttr(tag, "r:link")] {
+            return removeXMLAttr(tag, "r:link")
+        }
+        return tag
+    })
+}
+
+// cleanExternalLinks 处理外部关系(UNC 路径、内网地址、链接文件)
+// remove: 删除链接，仍被其他内容引用的关系目标改为 about:blank
+// unlink: 图片有嵌入副本时去掉外部链接
+// map: 按 -linkmap 规则改写链接目标
+func cleanExternalLinks(dir, policy string) error {
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        ids := map[string]bool{}
+        for _, r := range readRels(dir, rels) {
+            if r.external() {
+                ids[r.ID] = true
+            }
+        }
+        if len(ids) == 0 {
+            continue
+        }
+
+        if policy == "map" {
+            err := setRelTargets(dir, rels, func(r relationship) string {
+                if !r.external() {
+                    return ""
+                }
+                t := xmlUnescape(r.Target)
+                for _, rule := range linkRules {
+                    t = rule.re.ReplaceAllString(t, rule.repl)
+                }
+                if t != xmlUnescape(r.Target) {
+                    logPrintf("改写外部链接: %s → %s", xmlUnescape(r.Target), t)
+                }
+                return t
+            })
+            if err != nil {
+                return err
+            }
+            continue
+        }
+
+        data, err := readPart(dir, source)
+        if err != nil {
+            data = nil
+        }
+        text := string(data)
+        if policy == "unlink" {
+            text = unlinkPictures(text, ids)
+        } else {
+            text = removeLinkRefs(text, ids)
+        }
+        if data != nil && text != string(data) {
+            if err := writePart(dir, source, []byte(text)); err != nil {
+                return err
+            }
+        }
+
+        // remove 删除所有不再被引用的外部关系；unlink 只删除本次去掉了引用的关系，
+        // 包级关系和本来就没有在部件中找到引用的关系保持不变
+        usedBefore := map[string]bool{}
+        for _, m := range relIDRe.FindAllStringSubmatch(string(data), -1) {
+            usedBefore[m[1]] = true
+        }
+        used := map[string]bool{}
+        for _, m := range relIDRe.FindAllStringSubmatch(text, -1) {
+            used[m[1]] = true
+        }
+        removed, err := removeRels(dir, rels, func(r relationship) bool {
+            if !r.external() || used[r.ID] {
+                return false
+            }
+            return policy == "remove" || usedBefore[r.ID]
+        })
+        if err != nil {
+            return err
+        }
+        for _, r := range removed {
+            logPrintf("删除外部链接: %s %s", source, xmlUnescape(r.Target))
+        }
+        if policy == "remove" {
+            err := setRelTargets(dir, rels, func(r relationship) string {
+                if !r.external() {
+                    return ""
+                }
+                logPrintf("清除外部链接目标: %s %s", source, xmlUnescape(r.Target))
+                return "about:blank"
+            })
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func inspectExternalLinks(dir string) {
+    for _, rels := range listRelsParts(dir) {
+        for _, r := range readRels(dir, rels) {
+            if r.external() {
+                reportPrintf("  外部链接: %s %s %s", rels, path.Base(r.Type), xmlUnescape(r.Target))
+            }
+        }
+    }
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+const (
+    relVbaProject      = "http://schemas.microsoft.com/office/2006/relationships/vbaProject"
+    relKeyMapCustom    = "http://schemas.microsoft.com/office/2006/relationships/keyMapCustomizations"
+    relAttachedToolbar = "http://schemas.microsoft.com/office/2006/relationships/attachedToolbars"
+    relXlMacrosheet    = "http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet"
+    relXlIntlMacro     = "http://schemas.microsoft.com/office/2006/relationships/xlIntlMacrosheet"
+)
+
+var errOnlyMacroSheets = errors.New("工作簿只有 Excel 4.0 宏表，无法去除宏")
+
+// 启用宏的主文档内容类型 → 不含宏的内容类型
+var macroFreeContentTypes = map[string]string{
+    "application/vnd.ms-word.document.macroEnabled.main+xml":           "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml",
+    "application/vnd.ms-excel.sheet.macroEnabled.main+xml":             "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml",
+    "application/vnd.ms-powerpoint.presentation.macroEnabled.main+xml": "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml",
+    "application/vnd.ms-powerpoint.slideshow.macroEnabled.main+xml":    "application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml",
+}
+
+var macroFreeExts = map[string]string{
+    ".docm": ".docx",
+    ".xlsm": ".xlsx",
+    ".pptm": ".pptx",
+}
+
+// macroFreeName 返回去除宏后的输出文件名，非启用宏格式原样返回；
+// 同名文件已存在时追加序号，如 foo (1).docx，不覆盖已有文件
+func macroFreeName(filePath string) string {
+    ext := filepath.Ext(filePath)
+    newExt, ok := macroFreeExts[strings.ToLower(ext)]
+    if !ok {
+        return filePath
+    }
+    base := strings.TrimSuffix(filePath, ext)
+    name := base + newExt
+    for i := 1; ; i++ {
+        if _, err := os.Stat(name); os.IsNotExist(err) {
+            return name
+        }
+        name = fmt.Sprintf("%s (%d)%s", base, i, newExt)
+    }
+}
+
+func isMacroSheet(s sheetInfo) bool {
+    return s.relType == relXlMacrosheet || s.relType == relXlIntlMacro
+}
+
+// removeMacros 删除 VBA 工程（含 vbaData.xml、签名）、Excel 4.0 宏表及 Word 宏快捷键/工具栏部件，
+// 并把主文档内容类型改为不含宏的版本
+func removeMacros(dir string) error {
+    main := mainDocumentPart(dir)
+    if main == "" {
+        return nil
+    }
+
+    for _, relType := range []string{relVbaProject, relKeyMapCustom, relAttachedToolbar} {
+        for _, target := range findRelsByType(dir, main, relType) {
+            logPrintf("删除宏部件: %s", target)
+            if err := removePartTree(dir, target); err != nil {
+                return err
+            }
+        }
+    }
+
+    // Excel 4.0 宏表以工作表形式存在，连同宏名称一起删除
+    if wb := workbookPart(dir); wb != "" {
+        macroSheets := 0
+        for _, s := range workbookSheets(dir, wb) {
+            if isMacroSheet(s) {
+                macroSheets++
+            }
+        }
+        if macroSheets > 0 {
+            deleted, err := deleteSheets(dir, wb, isMacroSheet, func(tag string) bool {
+                return xmlAttr(tag, "xlm") == "1" || xmlAttr(tag, "function") == "1" || xmlAttr(tag, "vbProcedure") == "1"
+            })
+            if err != nil {
+                return err
+            }
+            if !deleted {
+                return errOnlyMacroSheets
+            }
+        }
+        // 没有被工作表引用的宏表部件
+        for _, relType := range []string{relXlMacrosheet, relXlIntlMacro} {
+            for _, target := range findRelsByType(dir, wb, relType) {
+                logPrintf("删除宏部件: %s", target)
+                if err := removePartTree(dir, target); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+
+    if ct, ok := macroFreeContentTypes[contentTypeOf(dir, main)]; ok {
+        return setContentTypeOverride(dir, main, ct)
+    }
+    return nil
+}
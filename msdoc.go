@@ -0,0 +1,265 @@
+package main
+
+import (
+    "encoding/binary"
+    "fmt"
+)
+
+// Word 二进制格式(MS-DOC)，FibRgFcLcb97 中各结构的序号
+const (
+    fibSttbfAssoc  = 32
+    fibSttbfRMark  = 51
+    fibSttbSavedBy = 71
+
+    fibRgFcLcbOffset = 32
+
+    // Word 97 及以后版本 FIB 的最小要求
+    fibMinNFib = 0x00C1
+    fibMinCsw  = 0x000E
+    fibMinCslw = 0x0016
+)
+
+// fibSupported 判断 FIB 是否为 Word 97 及以后的格式，且 fc/lcb 表包含用到的所有结构
+func fibSupported(fib []byte) bool {
+    if len(fib) < fibRgFcLcbOffset+2 || binary.LittleEndian.Uint16(fib[2:]) < fibMinNFib {
+        return false
+    }
+    pos := fibRgFcLcbOffset
+    csw := int(binary.LittleEndian.Uint16(fib[pos:]))
+    pos += 2 + csw*2
+    if csw < fibMinCsw || pos+2 > len(fib) {
+        return false
+    }
+    cslw := int(binary.LittleEndian.Uint16(fib[pos:]))
+    pos += 2 + cslw*4
+    if cslw < fibMinCslw || pos+2 > len(fib) {
+        return false
+    }
+    cbRgFcLcb := int(binary.LittleEndian.Uint16(fib[pos:]))
+    return cbRgFcLcb > fibSttbSavedBy && cbRgFcLcb > fibSttbfRMark && cbRgFcLcb > fibSttbfAssoc
+}
+
+// fibFcLcbPos 返回 FIB 中第 index 个 fc/lcb 对的位置，不存在时返回 -1
+func fibFcLcbPos(fib []byte, index int) int {
+    pos := fibRgFcLcbOffset
+    if pos+2 > len(fib) {
+        return -1
+    }
+    csw := int(binary.LittleEndian.Uint16(fib[pos:]))
+    pos += 2 + csw*2
+    if pos+2 > len(fib) {
+        return -1
+    }
+    cslw := int(binary.LittleEndian.Uint16(fib[pos:]))
+    pos += 2 + cslw*4
+    if pos+2 > len(fib) {
+        return -1
+    }
+    count := int(binary.LittleEndian.Uint16(fib[pos:]))
+    pos += 2 + index*8
+    if index >= count || pos+8 > len(fib) {
+        return -1
+    }
+    return pos
+}
+
+// blankSttb 把 STTB 中的字符串全部改为空串，保留条目数和附加数据长度，返回新的长度
+func blankSttb(b []byte) (int, error) {
+    if len(b) < 4 {
+        return 0, fmt.Errorf("STTB 长度无效")
+    }
+    extended := binary.LittleEndian.Uint16(b) == 0xFFFF
+    pos := 0
+    if extended {
+        pos = 2
+    }
+    if pos+4 > len(b) {
+        return 0, fmt.Errorf("STTB 长度无效")
+    }
+    cData := int(binary.LittleEndian.Uint16(b[pos:]))
+    cbExtra := int(binary.LittleEndian.Uint16(b[pos+2:]))
+    pos += 4
+    cchSize := 1
+    if extended {
+        cchSize = 2
+    }
+    size := pos + cData*(cchSize+cbExtra)
+    if size > len(b) {
+        return 0, fmt.Errorf("STTB 长度无效")
+    }
+    header := append([]byte(nil), b[:pos]...)
+    for i := range b {
+        b[i] = 0
+    }
+    copy(b, header)
+    return size, nil
+}
+
+// cleanWordBinary 清空 Word 二进制文档中的保存历史(SttbSavedBy)、
+// 关联模板和作者(SttbfAssoc)以及修订作者表(SttbfRMark)
+func cleanWordBinary(f *cfbFile) error {
+    doc := f.Root.find("WordDocument")
+    if doc == nil || len(doc.Data) < fibRgFcLcbOffset {
+        return nil
+    }
+    fib := append([]byte(nil), doc.Data...)
+    if binary.LittleEndian.Uint16(fib) != 0xA5EC {
+        return fmt.Errorf("WordDocument 流不是有效的 FIB")
+    }
+    if !fibSupported(fib) {
+        logPrintf("Word 文档版本早于 Word 97 或 FIB 不完整，跳过 Word 保存历史清理")
+        return nil
+    }
+    flags := binary.LittleEndian.Uint16(fib[10:])
+    if flags&0x0100 != 0 {
+        logPrintf("文档已加密，跳过 Word 保存历史清理")
+        return nil
+    }
+    tableName := "0Table"
+    if flags&0x0200 != 0 {
+        tableName = "1Table"
+    }
+    table := f.Root.find(tableName)
+    if table == nil {
+        return fmt.Errorf("缺少 %s 流", tableName)
+    }
+    tbl := append([]byte(nil), table.Data...)
+
+    for _, s := range []struct {
+        index int
+        name  string
+    }{{fibSttbSavedBy, "保存历史"}, {fibSttbfAssoc, "关联模板和作者"}, {fibSttbfRMark, "修订作者"}} {
+        pos := fibFcLcbPos(fib, s.index)
+        if pos < 0 {
+            continue
+        }
+        fc := int(binary.LittleEndian.Uint32(fib[pos:]))
+        lcb := int(binary.LittleEndian.Uint32(fib[pos+4:]))
+        if lcb == 0 {
+            continue
+        }
+        if fc+lcb > len(tbl) {
+            return fmt.Errorf("FIB 中%s位置无效", s.name)
+        }
+        region := tbl[fc : fc+lcb]
+
+        // 保存历史是可选结构，直接删除；另外两个保留条目数，只清空字符串
+        if s.index == fibSttbSavedBy {
+            for i := range region {
+                region[i] = 0
+            }
+            binary.LittleEndian.PutUint32(fib[pos+4:], 0)
+        } else {
+            size, err := blankSttb(region)
+            if err != nil {
+                return err
+            }
+            binary.LittleEndian.PutUint32(fib[pos+4:], uint32(size))
+        }
+        logPrintf("清空 Word %s", s.name)
+    }
+
+    if err := f.writeStream(doc, fib); err != nil {
+        return err
+    }
+    return f.writeStream(table, tbl)
+}
+
+// sttbStrings 读取 STTB 中的字符串
+func sttbStrings(b []byte) []string {
+    if len(b) < 4 {
+        return nil
+    }
+    extended := binary.LittleEndian.Uint16(b) == 0xFFFF
+    pos := 0
+    if extended {
+        pos = 2
+    }
+    if pos+4 > len(b) {
+        return nil
+    }
+    cData := int(binary.LittleEndian.Uint16(b[pos:]))
+    cbExtra := int(binary.LittleEndian.Uint16(b[pos+2:]))
+    pos += 4
+    var list []string
+    for i := 0; i < cData; i++ {
+        var s string
+        if extended {
+            if pos+2 > len(b) {
+                break
+            }
+            cch := int(binary.LittleEndian.Uint16(b[pos:]))
+            pos += 2
+            if pos+cch*2 > len(b) {
+                break
+            }
+            s = decodeUTF16LE(b[pos : pos+cch*2])
+            pos += cch * 2
+        } else {
+            if pos+1 > len(b) {
+                break
+            }
+            cch := int(b[pos])
+            pos++
+            if pos+cch > len(b) {
+                break
+            }
+            s = string(b[pos : pos+cch])
+            pos += cch
+        }
+        pos += cbExtra
+        list = append(list, s)
+    }
+    return list
+}
+
+func inspectWordBinary(f *cfbFile) {
+    doc := f.Root.find("WordDocument")
+    if doc == nil || len(doc.Data) < fibRgFcLcbOffset || binary.LittleEndian.Uint16(doc.Data) != 0xA5EC {
+        return
+    }
+    flags := binary.LittleEndian.Uint16(doc.Data[10:])
+    if flags&0x0100 != 0 {
+        reportPrintf("  文档已加密")
+        return
+    }
+    if !fibSupported(doc.Data) {
+        reportPrintf("  Word 文档版本早于 Word 97 或 FIB 不完整，跳过检查")
+        return
+    }
+    tableName := "0Table"
+    if flags&0x0200 != 0 {
+        tableName = "1Table"
+    }
+    table := f.Root.find(tableName)
+    if table == nil {
+        return
+    }
+    read := func(index int) []string {
+        pos := fibFcLcbPos(doc.Data, index)
+        if pos < 0 {
+            return nil
+        }
+        fc := int(binary.LittleEndian.Uint32(doc.Data[pos:]))
+        lcb := int(binary.LittleEndian.Uint32(doc.Data[pos+4:]))
+        if lcb == 0 || fc+lcb > len(table.Data) {
+            return nil
+        }
+        return sttbStrings(table.Data[fc : fc+lcb])
+    }
+
+    saved := read(fibSttbSavedBy)
+    for i := 0; i+1 < len(saved); i += 2 {
+        reportPrintf("  保存历史: %s %s", saved[i], saved[i+1])
+    }
+    for _, s := range read(fibSttbfAssoc) {
+        if s != "" {
+            reportPrintf("  关联信息: %s", s)
+        }
+    }
+    for _, s := range read(fibSttbfRMark) {
+        if s != "" {
+            reportPrintf("  修订作者: %s", s)
+        }
+    }
+}
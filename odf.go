@@ -0,0 +1,287 @@
+package main
+
+import (
+    "os"
+    "sort"
+    "strings"
+)
+
+// OpenDocument(odt/ods/odp)包
+const (
+    odfMimetypePart = "mimetype"
+    odfManifestPart = "META-INF/manifest.xml"
+    odfMimePrefix   = "application/vnd.oasis.opendocument."
+)
+
+// meta.xml 中需要删除的元素
+var odfMetaElements = []struct {
+    name  string
+    label string
+}{
+    {"meta:initial-creator", "创建者"},
+    {"dc:creator", "作者"},
+    {"meta:printed-by", "打印者"},
+    {"meta:creation-date", "创建时间"},
+    {"dc:date", "修改时间"},
+    {"meta:print-date", "打印时间"},
+    {"meta:editing-cycles", "编辑次数"},
+    {"meta:editing-duration", "编辑时长"},
+    {"meta:generator", "生成程序"},
+    {"meta:user-defined", "自定义属性"},
+}
+
+var (
+    odfSignatureParts = []string{"META-INF/documentsignatures.xml", "META-INF/macrosignatures.xml", "META-INF/packagesignatures.xml"}
+    odfPrinterItems   = map[string]bool{"PrinterName": true, "PrinterSetup": true}
+    odfChangeMarks    = []string{"text:change", "text:change-start", "text:change-end"}
+)
+
+func isODFPackage(dir string) bool {
+    data, err := readPart(dir, odfMimetypePart)
+    return err == nil && strings.HasPrefix(string(data), odfMimePrefix)
+}
+
+func odfSigned(dir string) bool {
+    for _, p := range odfSignatureParts {
+        if partExists(dir, p) {
+            return true
+        }
+    }
+    return false
+}
+
+// odfManifestEntries 返回清单中的文件条目，键为路径，值为条目文本
+func odfManifestEntries(dir string) map[string]string {
+    data, err := readPart(dir, odfManifestPart)
+    if err != nil {
+        return nil
+    }
+    text := string(data)
+    entries := map[string]string{}
+    for _, s := range xmlElementSpans(text, "manifest:file-entry") {
+        elem := text[s[0]:s[1]]
+        entries[xmlUnescape(xmlAttr(elem, "manifest:full-path"))] = elem
+    }
+    return entries
+}
+
+// odfEncrypted 判断部件是否在清单中标记为加密，加密部件无法按 XML 处理
+func odfEncrypted(dir, part string) bool {
+    return strings.Contains(odfManifestEntries(dir)[part], "manifest:encryption-data")
+}
+
+// removeManifestEntries 删除清单中路径满足条件的条目
+func removeManifestEntries(dir string, match func(path string) bool) error {
+    data, err := readPart(dir, odfManifestPart)
+    if err != nil {
+        return nil
+    }
+    text := string(data)
+    var spans [][2]int
+    for _, s := range xmlElementSpans(text, "manifest:file-entry") {
+        if match(xmlUnescape(xmlAttr(text[s[0]:s[1]], "manifest:full-path"))) {
+            spans = append(spans, s)
+        }
+    }
+    if len(spans) == 0 {
+        return nil
+    }
+    return writePart(dir, odfManifestPart, []byte(removeSpans(text, spans)))
+}
+
+// editODFPart 对未加密的 XML 部件做文本修改，edit 返回 false 表示没有改动
+func editODFPart(dir, part string, edit func(text string) (string, bool)) error {
+    data, err := readPart(dir, part)
+    if err != nil {
+        return nil
+    }
+    if odfEncrypted(dir, part) {
+        logPrintf("部件已加密，跳过: %s", part)
+        return nil
+    }
+    text, changed := edit(string(data))
+    if !changed {
+        return nil
+    }
+    return writePart(dir, part, []byte(text))
+}
+
+func elementSpans(text string, names ...string) [][2]int {
+    var spans [][2]int
+    for _, n := range names {
+        spans = append(spans, xmlElementSpans(text, n)...)
+    }
+    sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+    return spans
+}
+
+// cleanODF 清理 OpenDocument 包: meta.xml 中的作者、时间和统计信息，缩略图，
+// 修订记录，settings.xml 中的打印机配置，以及因修改而失效的数字签名
+func cleanODF(dir string) error {
+    var signed []string
+    for _, p := range odfSignatureParts {
+        if partExists(dir, p) {
+            os.Remove(partPath(dir, p))
+            signed = append(signed, p)
+            logPrintf("删除数字签名: %s", p)
+        }
+    }
+    if len(signed) > 0 {
+        if err := removeManifestEntries(dir, func(p string) bool {
+            for _, s := range signed {
+                if p == s {
+                    return true
+                }
+            }
+            return false
+        }); err != nil {
+            return err
+        }
+    }
+
+    err := editODFPart(dir, "meta.xml", func(text string) (string, bool) {
+        var names []string
+        for _, m := range odfMetaElements {
+            names = append(names, m.name)
+        }
+        spans := elementSpans(text, names...)
+        if len(spans) == 0 {
+            return text, false
+        }
+        logPrintf("清理 meta.xml: 删除 %d 项元数据", len(spans))
+        return removeSpans(text, spans), true
+    })
+    if err != nil {
+        return err
+    }
+
+    if _, err := os.Stat(partPath(dir, "Thumbnails")); err == nil {
+        os.RemoveAll(partPath(dir, "Thumbnails"))
+        logPrintf("删除缩略图")
+        if err := removeManifestEntries(dir, func(p string) bool {
+            return strings.HasPrefix(p, "Thumbnails/")
+        }); err != nil {
+            return err
+        }
+    }
+
+    // 删除修订区域和正文中的修订标记，相当于接受全部修订
+    err = editODFPart(dir, "content.xml", func(text string) (string, bool) {
+        regions := elementSpans(text, "text:tracked-changes", "table:tracked-changes")
+        if len(regions) == 0 {
+            return text, false
+        }
+        text = removeSpans(text, regions)
+        text = removeSpans(text, elementSpans(text, odfChangeMarks...))
+        logPrintf("删除修订记录")
+        return text, true
+    })
+    if err != nil {
+        return err
+    }
+
+    return editODFPart(dir, "settings.xml", func(text string) (string, bool) {
+        var spans [][2]int
+        for _, s := range xmlElementSpans(text, "config:config-item") {
+            if odfPrinterItems[xmlAttr(text[s[0]:s[1]], "config:name")] {
+                spans = append(spans, s)
+            }
+        }
+        if len(spans) == 0 {
+            return text, false
+        }
+        logPrintf("删除打印机配置")
+        return removeSpans(text, spans), true
+    })
+}
+
+// validateODF 检查 OpenDocument 包结构，返回发现的问题
+func validateODF(dir string) []string {
+    var problems []string
+    if !isODFPackage(dir) {
+        problems = append(problems, "缺少 "+odfMimetypePart)
+    }
+    entries := odfManifestEntries(dir)
+    if entries == nil {
+        return append(problems, "缺少 "+odfManifestPart)
+    }
+    for p := range entries {
+        if p != "/" && !strings.HasSuffix(p, "/") && !partExists(dir, p) {
+            problems = append(problems, odfManifestPart+": 条目不存在 "+p)
+        }
+    }
+    for _, p := range listParts(dir) {
+        if !strings.HasSuffix(p, ".xml") || strings.Contains(entries[p], "manifest:encryption-data") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        if err := isWellFormedXML(data); err != nil {
+            problems = append(problems, p+": XML 格式错误 "+err.Error())
+        }
+    }
+    sort.Strings(problems)
+    return problems
+}
+
+func inspectODF(dir string) {
+    if odfSigned(dir) {
+        reportPrintf("  数字签名: 有")
+    }
+    if data, err := readPart(dir, "meta.xml"); err == nil {
+        text := string(data)
+        for _, m := range odfMetaElements {
+            for _, s := range xmlElementSpans(text, m.name) {
+                elem := text[s[0]:s[1]]
+                value := elem[strings.Index(elem, ">")+1:]
+                if i := strings.LastIndex(value, "</"); i >= 0 {
+                    value = value[:i]
+                }
+                if m.name == "meta:user-defined" {
+                    value = xmlAttr(elem, "meta:name") + " = " + value
+                }
+                reportPrintf("  元数据: %s: %s", m.label, xmlUnescape(value))
+            }
+        }
+    }
+    if _, err := os.Stat(partPath(dir, "Thumbnails")); err == nil {
+        reportPrintf("  缩略图: 有")
+    }
+    if data, err := readPart(dir, "content.xml"); err == nil && !odfEncrypted(dir, "content.xml") {
+        text := string(data)
+        authors := map[string]bool{}
+        changes := 0
+        for _, r := range elementSpans(text, "text:tracked-changes", "table:tracked-changes") {
+            region := text[r[0]:r[1]]
+            changes += len(elementSpans(region, "text:changed-region", "table:cell-content-change",
+                "table:insertion", "table:deletion", "table:movement"))
+            for _, s := range xmlElementSpans(region, "dc:creator") {
+                elem := region[s[0]:s[1]]
+                authors[xmlUnescape(strings.TrimSuffix(elem[len("<dc:creator>"):], "</dc:creator>"))] = true
+            }
+        }
+        if changes > 0 {
+            var names []string
+            for a := range authors {
+                names = append(names, a)
+            }
+            sort.Strings(names)
+            reportPrintf("  修订记录: %d 处，作者 %s", changes, strings.Join(names, ", "))
+        }
+    }
+    if data, err := readPart(dir, "settings.xml"); err == nil && !odfEncrypted(dir, "settings.xml") {
+        text := string(data)
+        for _, s := range xmlElementSpans(text, "config:config-item") {
+            elem := text[s[0]:s[1]]
+            if xmlAttr(elem, "config:name") == "PrinterName" {
+                name := elem[strings.Index(elem, ">")+1:]
+                name = strings.TrimSuffix(name, "</config:config-item>")
+                if name != "" {
+                    reportPrintf("  打印机: %s", xmlUnescape(name))
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+    "encoding/xml"
+    "html"
+    "os"
+    "path"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+const contentTypesPart = "[Content_Types].xml"
+
+var (
+    relationshipRe = regexp.MustCompile(`(?s)<Relationship\b[^>]*?(?:/>|>.*?</Relationship>)`)
+    overrideRe     = regexp.MustCompile(`(?s)<Override\b[^>]*?(?:/>|>.*?</Override>)`)
+    defaultRe      = regexp.MustCompile(`(?s)<Default\b[^>]*?(?:/>|>.*?</Default>)`)
+    relIDRe        = regexp.MustCompile(`\s(?:r:(?:id|embed|link|pict|dm|lo|qs|cs|href)|o:relid)="([^"]*)"`)
+)
+
+type relationship struct {
+    ID         string
+    Type       string
+    Target     string
+    TargetMode string
+    raw        string
+}
+
+// 包内部件名统一使用 "/" 分隔，且不带前导 "/"
+func partPath(dir, name string) string {
+    return filepath.Join(dir, filepath.FromSlash(name))
+}
+
+func readPart(dir, name string) ([]byte, error) {
+    return os.ReadFile(partPath(dir, name))
+}
+
+func writePart(dir, name string, data []byte) error {
+    p := partPath(dir, name)
+    os.MkdirAll(filepath.Dir(p), 0755)
+    return os.WriteFile(p, data, 0644)
+}
+
+func partExists(dir, name string) bool {
+    info, err := os.Stat(partPath(dir, name))
+    return err == nil && !info.IsDir()
+}
+
+func listParts(dir string) []string {
+    var parts []string
+    filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+        if err != nil || info.IsDir() {
+            return nil
+        }
+        rel, err := filepath.Rel(dir, p)
+        if err == nil {
+            parts = append(parts, filepath.ToSlash(rel))
+        }
+        return nil
+    })
+    return parts
+}
+
+// xmlAttr 从单个标签文本中取属性值，兼容单双引号
+func xmlAttr(tag, name string) string {
+    re := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+    m := re.FindStringSubmatch(tag)
+    if m == nil {
+        return ""
+    }
+    return m[1] + m[2]
+}
+
+// relsPartFor 返回部件对应的关系文件，空字符串表示包级关系 _rels/.rels
+func relsPartFor(part string) string {
+    dir, file := path.Split(part)
+    return dir + "_rels/" + file + ".rels"
+}
+
+// sourceOfRels 为 relsPartFor 的逆运算
+func sourceOfRels(rels string) string {
+    dir, file := path.Split(rels)
+    dir = strings.TrimSuffix(dir, "_rels/")
+    return dir + strings.TrimSuffix(file, ".rels")
+}
+
+// resolveTarget 把关系中的相对 Target 解析为包内部件名
+func resolveTarget(source, target string) string {
+    if i := strings.IndexAny(target, "#?"); i >= 0 {
+        target = target[:i]
+    }
+    if strings.HasPrefix(target, "/") {
+        return strings.TrimPrefix(path.Clean(target), "/")
+    }
+    base := path.Dir(source)
+    if base == "." {
+        base = ""
+    }
+    return strings.TrimPrefix(path.Clean("/"+base+"/"+target), "/")
+}
+
+func readRels(dir, rels string) []relationship {
+    data, err := readPart(dir, rels)
+    if err != nil {
+        return nil
+    }
+    var list []relationship
+    for _, raw := range relationshipRe.FindAllString(string(data), -1) {
+        list = append(list, relationship{
+            ID:         xmlAttr(raw, "Id"),
+            Type:       xmlAttr(raw, "Type"),
+            Target:     xmlAttr(raw, "Target"),
+            TargetMode: xmlAttr(raw, "TargetMode"),
+            raw:        raw,
+        })
+    }
+    return list
+}
+
+func (r relationship) external() bool {
+    return strings.EqualFold(r.TargetMode, "External")
+}
+
+// removeRels 删除关系文件中满足条件的关系，返回被删除的关系
+func removeRels(dir, rels string, match func(relationship) bool) ([]relationship, error) {
+    data, err := readPart(dir, rels)
+    if err != nil {
+        return nil, nil
+    }
+    text := string(data)
+    var removed []relationship
+    for _, r := range readRels(dir, rels) {
+        if match(r) {
+            text = strings.Replace(text, r.raw, "", 1)
+            removed = append(removed, r)
+        }
+    }
+    if len(removed) == 0 {
+        return nil, nil
+    }
+    return removed, writePart(dir, rels, []byte(text))
+}
+
+func listRelsParts(dir string) []string {
+    var list []string
+    for _, p := range listParts(dir) {
+        if strings.HasSuffix(p, ".rels") && strings.Contains(p, "_rels/") {
+            list = append(list, p)
+        }
+    }
+    return list
+}
+
+// findRelsByType 查找指定部件上某类型的内部关系目标
+func findRelsByType(dir, source, relType string) []string {
+    var targets []string
+    for _, r := range readRels(dir, relsPartFor(source)) {
+        if r.Type == relType && !r.external() {
+            targets = append(targets, resolveTarget(source, r.Target))
+        }
+    }
+    return targets
+}
+
+// mainDocumentPart 通过包级关系找到主文档部件
+func mainDocumentPart(dir string) string {
+    for _, r := range readRels(dir, "_rels/.rels") {
+        if strings.HasSuffix(r.Type, "/officeDocument") && !r.external() {
+            return resolveTarget("", r.Target)
+        }
+    }
+    return ""
+}
+
+// removePart 删除部件及其关系文件，并清理所有指向它的关系和内容类型声明
+func removePart(dir, name string) error {
+    os.Remove(partPath(dir, name))
+    os.Remove(partPath(dir, relsPartFor(name)))
+
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        _, err := removeRels(dir, rels, func(r relationship) bool {
+            return !r.external() && strings.EqualFold(resolveTarget(source, r.Target), name)
+        })
+        if err != nil {
+            return err
+        }
+    }
+    return removeContentTypeOverride(dir, name)
+}
+
+// removePartTree 删除部件，并递归删除只被它引用的子部件
+func removePartTree(dir, name string) error {
+    children := []string{}
+    for _, r := range readRels(dir, relsPartFor(name)) {
+        if !r.external() {
+            children = append(children, resolveTarget(name, r.Target))
+        }
+    }
+    if err := removePart(dir, name); err != nil {
+        return err
+    }
+    for _, c := range children {
+        if partExists(dir, c) && !isPartReferenced(dir, c) {
+            if err := removePartTree(dir, c); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func isPartReferenced(dir, name string) bool {
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        for _, r := range readRels(dir, rels) {
+            if !r.external() && strings.EqualFold(resolveTarget(source, r.Target), name) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// removeDroppedRels 删除 ids 中已不再被部件内容引用的关系，目标部件不再被引用时一并删除
+func removeDroppedRels(dir, source, text string, ids []string) error {
+    used := map[string]bool{}
+    for _, m := range relIDRe.FindAllStringSubmatch(text, -1) {
+        used[m[1]] = true
+    }
+    dropped := map[string]bool{}
+    for _, id := range ids {
+        if !used[id] {
+            dropped[id] = true
+        }
+    }
+    removed, err := removeRels(dir, relsPartFor(source), func(r relationship) bool {
+        return dropped[r.ID]
+    })
+    if err != nil {
+        return err
+    }
+    for _, r := range removed {
+        t := resolveTarget(source, r.Target)
+        if !r.external() && partExists(dir, t) && !isPartReferenced(dir, t) {
+            if err := removePartTree(dir, t); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func removeContentTypeOverride(dir, name string) error {
+    data, err := readPart(dir, contentTypesPart)
+    if err != nil {
+        return nil
+    }
+    text := string(data)
+    changed := false
+    for _, o := range overrideRe.FindAllString(text, -1) {
+        if strings.EqualFold(strings.TrimPrefix(xmlAttr(o, "PartName"), "/"), name) {
+            text = strings.Replace(text, o, "", 1)
+            changed = true
+        }
+    }
+    if !changed {
+        return nil
+    }
+    return writePart(dir, contentTypesPart, []byte(text))
+}
+
+func setContentTypeOverride(dir, name, contentType string) error {
+    data, err := readPart(dir, contentTypesPart)
+    if err != nil {
+        return err
+    }
+    text := string(data)
+    n := `<Override PartName="/` + name + `" ContentType="` + contentType + `"/>`
+    for _, o := range overrideRe.FindAllString(text, -1) {
+        if strings.EqualFold(strings.TrimPrefix(xmlAttr(o, "PartName"), "/"), name) {
+            text = strings.Replace(text, o, n, 1)
+            return writePart(dir, contentTypesPart, []byte(text))
+        }
+    }
+    text = strings.Replace(text, "</Types>", n+"</Types>", 1)
+    return writePart(dir, contentTypesPart, []byte(text))
+}
+
+// contentTypeOf 先查 Override，再按扩展名查 Default
+func contentTypeOf(dir, name string) string {
+    data, err := readPart(dir, contentTypesPart)
+    if err != nil {
+        return ""
+    }
+    text := string(data)
+    for _, o := range overrideRe.FindAllString(text, -1) {
+        if strings.EqualFold(strings.TrimPrefix(xmlAttr(o, "PartName"), "/"), name) {
+            return xmlAttr(o, "ContentType")
+        }
+    }
+    ext := strings.TrimPrefix(path.Ext(name), ".")
+    for _, d := range defaultRe.FindAllString(text, -1) {
+        if strings.EqualFold(xmlAttr(d, "Extension"), ext) {
+            return xmlAttr(d, "ContentType")
+        }
+    }
+    return ""
+}
+
+func attrRe(name string) *regexp.Regexp {
+    return regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*(?:"[^"]*"|'[^']*')`)
+}
+
+// setXMLAttr 修改标签中的属性值，属性不存在时追加，value 需已转义
+func setXMLAttr(tag, name, value string) string {
+    re := attrRe(name)
+    attr := " " + name + `="` + value + `"`
+    if re.MatchString(tag) {
+        return re.ReplaceAllLiteralString(tag, attr)
+    }
+    end := strings.LastIndex(tag, "/>")
+    if end < 0 || end != len(tag)-2 {
+        end = strings.Index(tag, ">")
+    }
+    if end < 0 {
+        return tag
+    }
+    return tag[:end] + attr + tag[end:]
+}
+
+func removeXMLAttr(tag, name string) string {
+    return attrRe(name).ReplaceAllLiteralString(tag, "")
+}
+
+func xmlEscape(s string) string {
+    var b strings.Builder
+    xml.EscapeText(&b, []byte(s))
+    return b.String()
+}
+
+func xmlUnescape(s string) string {
+    return html.UnescapeString(s)
+}
+
+// xmlElementSpans 返回文本中所有名为 qname 的元素区间(含嵌套)，按起始位置排序
+func xmlElementSpans(text, qname string) [][2]int {
+    re := regexp.MustCompile(`<` + regexp.QuoteMeta(qname) + `(?:\s[^>]*)?>|</` + regexp.QuoteMeta(qname) + `\s*>`)
+    var spans [][2]int
+    var stack []int
+    for _, m := range re.FindAllStringIndex(text, -1) {
+        tag := text[m[0]:m[1]]
+        switch {
+        case strings.HasPrefix(tag, "</"):
+            if len(stack) > 0 {
+                start := stack[len(stack)-1]
+                stack = stack[:len(stack)-1]
+                spans = append(spans, [2]int{start, m[1]})
+            }
+        case strings.HasSuffix(tag, "/>"):
+            spans = append(spans, [2]int{m[0], m[1]})
+        default:
+            stack = append(stack, m[0])
+        }
+    }
+    sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+    return spans
+}
+
+// removeSpans 删除选中的区间，被外层区间包含的自动忽略
+func removeSpans(text string, spans [][2]int) string {
+    var b strings.Builder
+    pos := 0
+    for _, s := range spans {
+        if s[0] < pos {
+            continue
+        }
+        b.WriteString(text[pos:s[0]])
+        pos = s[1]
+    }
+    b.WriteString(text[pos:])
+    return b.String()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+    "os"
+    "strings"
+)
+
+// reachableParts 从 _rels/.rels 出发沿内部关系遍历，返回可到达的部件(小写)
+func reachableParts(dir string) map[string]bool {
+    seen := map[string]bool{}
+    queue := []string{""}
+    for len(queue) > 0 {
+        source := queue[0]
+        queue = queue[1:]
+        for _, r := range readRels(dir, relsPartFor(source)) {
+            if r.external() {
+                continue
+            }
+            t := resolveTarget(source, r.Target)
+            if seen[strings.ToLower(t)] || !partExists(dir, t) {
+                continue
+            }
+            seen[strings.ToLower(t)] = true
+            queue = append(queue, t)
+        }
+    }
+    return seen
+}
+
+// orphanParts 返回没有任何关系链可以到达的部件，关系文件随其源部件一起判断
+func orphanParts(dir string) []string {
+    reachable := reachableParts(dir)
+    var orphans []string
+    for _, p := range listParts(dir) {
+        if p == contentTypesPart {
+            continue
+        }
+        name := p
+        if strings.HasSuffix(p, ".rels") && strings.Contains(p, "_rels/") {
+            name = sourceOfRels(p)
+            if name == "" {
+                continue
+            }
+        }
+        if !reachable[strings.ToLower(name)] {
+            orphans = append(orphans, p)
+        }
+    }
+    return orphans
+}
+
+// removeOrphanParts 删除孤立部件及其内容类型声明
+func removeOrphanParts(dir string) error {
+    for _, p := range orphanParts(dir) {
+        logPrintf("删除孤立部件: %s", p)
+        if err := os.Remove(partPath(dir, p)); err != nil {
+            return err
+        }
+        if err := removeContentTypeOverride(dir, p); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectOrphanParts(dir string) {
+    for _, p := range orphanParts(dir) {
+        reportPrintf("  孤立部件: %s", p)
+    }
+}
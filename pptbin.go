@@ -0,0 +1,287 @@
+package main
+
+import (
+    "encoding/binary"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// PowerPoint 二进制格式(MS-PPT)
+const (
+    pptCurrentUserAtom    = 0x0FF6
+    pptUserEditAtom       = 0x0FF5
+    pptPersistDirectory   = 0x1772
+    pptHeaderToken        = 0xE391C05F
+    pptDocumentStream     = "PowerPoint Document"
+    pptCurrentUserStream  = "Current User"
+    pptMaxPersistPerEntry = 0xFFF
+)
+
+// 含持久化对象引用的记录类型 → 引用在记录体中的偏移
+var pptPersistRefs = map[uint16][]int{
+    0x03E9: {24, 28}, // DocumentAtom: notesMasterPersistIdRef, handoutMasterPersistIdRef
+    0x03F3: {0},      // SlidePersistAtom: 幻灯片、母版、备注
+    0x0400: {0},      // VBAInfoAtom
+    0x0FC3: {16},     // ExOleObjAtom: 嵌入对象、ActiveX 控件
+}
+
+type pptUserEdit struct {
+    offset int
+    body   []byte
+}
+
+// pptRecord 返回 offset 处记录的类型和完整长度(含 8 字节记录头)
+func pptRecord(data []byte, offset int) (uint16, int, bool) {
+    if offset < 0 || offset+8 > len(data) {
+        return 0, 0, false
+    }
+    typ := binary.LittleEndian.Uint16(data[offset+2:])
+    n := 8 + int(binary.LittleEndian.Uint32(data[offset+4:]))
+    return typ, n, offset+n <= len(data)
+}
+
+// pptUserEdits 从当前编辑开始沿 offsetLastEdit 返回所有编辑记录，最新的在前
+func pptUserEdits(data []byte, current int) ([]pptUserEdit, error) {
+    var edits []pptUserEdit
+    seen := map[int]bool{}
+    for offset := current; ; {
+        typ, n, ok := pptRecord(data, offset)
+        if !ok || typ != pptUserEditAtom || n < 8+28 || seen[offset] {
+            return nil, fmt.Errorf("PowerPoint 编辑记录链损坏")
+        }
+        seen[offset] = true
+        body := data[offset+8 : offset+n]
+        edits = append(edits, pptUserEdit{offset, body})
+        last := int(binary.LittleEndian.Uint32(body[8:]))
+        if last == 0 {
+            return edits, nil
+        }
+        offset = last
+    }
+}
+
+// pptPersistOffsets 合并所有编辑的持久化目录，较新的编辑优先
+func pptPersistOffsets(data []byte, edits []pptUserEdit) (map[uint32]int, error) {
+    offsets := map[uint32]int{}
+    for _, e := range edits {
+        dir := int(binary.LittleEndian.Uint32(e.body[12:]))
+        typ, n, ok := pptRecord(data, dir)
+        if !ok || typ != pptPersistDirectory {
+            return nil, fmt.Errorf("PowerPoint 持久化目录损坏")
+        }
+        for pos := dir + 8; pos+4 <= dir+n; {
+            v := binary.LittleEndian.Uint32(data[pos:])
+            id, count := v&0xFFFFF, int(v>>20)
+            pos += 4
+            for i := 0; i < count && pos+4 <= dir+n; i++ {
+                if _, ok := offsets[id+uint32(i)]; !ok {
+                    offsets[id+uint32(i)] = int(binary.LittleEndian.Uint32(data[pos:]))
+                }
+                pos += 4
+            }
+        }
+    }
+    return offsets, nil
+}
+
+// pptReachable 从文档容器开始，返回当前版本仍在引用的持久化对象编号
+func pptReachable(data []byte, offsets map[uint32]int, root uint32) (map[uint32]bool, error) {
+    if _, ok := offsets[root]; !ok {
+        return nil, fmt.Errorf("PowerPoint 文档容器不存在")
+    }
+    reached := map[uint32]bool{}
+    queue := []uint32{root}
+    var walk func(start, end int)
+    walk = func(start, end int) {
+        for pos := start; pos < end; {
+            typ, n, ok := pptRecord(data, pos)
+            if !ok || pos+n > end {
+                return
+            }
+            // recVer 为 0xF 的是容器
+            if data[pos]&0x0F == 0x0F {
+                walk(pos+8, pos+n)
+            }
+            for _, off := range pptPersistRefs[typ] {
+                if 8+off+4 <= n {
+                    queue = append(queue, binary.LittleEndian.Uint32(data[pos+8+off:]))
+                }
+            }
+            pos += n
+        }
+    }
+    for len(queue) > 0 {
+        id := queue[0]
+        queue = queue[1:]
+        offset, ok := offsets[id]
+        if reached[id] || !ok {
+            continue
+        }
+        reached[id] = true
+        _, n, ok := pptRecord(data, offset)
+        if !ok {
+            return nil, fmt.Errorf("PowerPoint 持久化对象越界")
+        }
+        walk(offset, offset+n)
+    }
+    return reached, nil
+}
+
+// compactPowerPoint 只保留当前版本引用的对象，重建持久化目录和唯一的编辑记录，
+// 删除快速保存留下的旧版本幻灯片
+func compactPowerPoint(data []byte, current int) ([]byte, int, error) {
+    edits, err := pptUserEdits(data, current)
+    if err != nil {
+        return nil, 0, err
+    }
+    if len(edits[0].body) > 28 {
+        return nil, 0, fmt.Errorf("演示文稿已加密")
+    }
+    offsets, err := pptPersistOffsets(data, edits)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    // 已删除的幻灯片在旧目录中仍有编号，只复制当前文档能引用到的对象
+    reached, err := pptReachable(data, offsets, binary.LittleEndian.Uint32(edits[0].body[16:]))
+    if err != nil {
+        return nil, 0, err
+    }
+    ids := make([]uint32, 0, len(reached))
+    for id := range reached {
+        ids = append(ids, id)
+    }
+    sort.Slice(ids, func(i, j int) bool { return offsets[ids[i]] < offsets[ids[j]] })
+
+    var out []byte
+    newOffsets := map[uint32]int{}
+    copied := map[int]int{}
+    for _, id := range ids {
+        old := offsets[id]
+        if pos, ok := copied[old]; ok {
+            newOffsets[id] = pos
+            continue
+        }
+        _, n, ok := pptRecord(data, old)
+        if !ok {
+            return nil, 0, fmt.Errorf("PowerPoint 持久化对象越界")
+        }
+        copied[old] = len(out)
+        newOffsets[id] = len(out)
+        out = append(out, data[old:old+n]...)
+    }
+
+    // 持久化目录，连续的编号合并为一项
+    sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+    var dir []byte
+    for i := 0; i < len(ids); {
+        j := i + 1
+        for j < len(ids) && ids[j] == ids[j-1]+1 && j-i < pptMaxPersistPerEntry {
+            j++
+        }
+        dir = binary.LittleEndian.AppendUint32(dir, ids[i]|uint32(j-i)<<20)
+        for _, id := range ids[i:j] {
+            dir = binary.LittleEndian.AppendUint32(dir, uint32(newOffsets[id]))
+        }
+        i = j
+    }
+    dirOffset := len(out)
+    out = binary.LittleEndian.AppendUint16(out, 0)
+    out = binary.LittleEndian.AppendUint16(out, pptPersistDirectory)
+    out = binary.LittleEndian.AppendUint32(out, uint32(len(dir)))
+    out = append(out, dir...)
+
+    editOffset := len(out)
+    body := append([]byte(nil), edits[0].body...)
+    binary.LittleEndian.PutUint32(body[8:], 0)
+    binary.LittleEndian.PutUint32(body[12:], uint32(dirOffset))
+    out = append(out, data[edits[0].offset:edits[0].offset+8]...)
+    out = append(out, body...)
+    return out, editOffset, nil
+}
+
+// currentUserName 返回 Current User 流中用户名的区间(ANSI 和 Unicode)
+func currentUserName(cu []byte) (ansi, uni [2]int, ok bool) {
+    if len(cu) < 8+20 || binary.LittleEndian.Uint16(cu[2:]) != pptCurrentUserAtom {
+        return ansi, uni, false
+    }
+    n := int(binary.LittleEndian.Uint16(cu[20:]))
+    ansi = [2]int{28, 28 + n}
+    if ansi[1] > len(cu) {
+        return ansi, uni, false
+    }
+    uni = [2]int{ansi[1] + 4, ansi[1] + 4 + n*2}
+    if uni[1] > len(cu) {
+        uni = [2]int{len(cu), len(cu)}
+    }
+    return ansi, uni, true
+}
+
+// cleanPowerPointBinary 清空 Current User 中的用户名并压缩编辑记录链，
+// 返回流长度是否改变(需要重建复合文档)
+func cleanPowerPointBinary(f *cfbFile) (bool, error) {
+    cuEntry := f.Root.find(pptCurrentUserStream)
+    doc := f.Root.find(pptDocumentStream)
+    if cuEntry == nil || doc == nil {
+        return false, nil
+    }
+    cu := append([]byte(nil), cuEntry.Data...)
+    ansi, uni, ok := currentUserName(cu)
+    if !ok {
+        return false, fmt.Errorf("Current User 流格式无效")
+    }
+    for i := ansi[0]; i < ansi[1]; i++ {
+        cu[i] = ' '
+    }
+    for i := uni[0]; i+1 < uni[1]; i += 2 {
+        cu[i], cu[i+1] = ' ', 0
+    }
+    logPrintf("清空 PowerPoint 当前用户名")
+
+    resized := false
+    if binary.LittleEndian.Uint32(cu[12:]) != pptHeaderToken {
+        logPrintf("演示文稿已加密，跳过编辑记录压缩")
+    } else {
+        current := int(binary.LittleEndian.Uint32(cu[16:]))
+        edits, err := pptUserEdits(doc.Data, current)
+        if err != nil {
+            return false, err
+        }
+        if len(edits) > 1 {
+            data, editOffset, err := compactPowerPoint(doc.Data, current)
+            if err != nil {
+                return false, err
+            }
+            doc.Data = data
+            binary.LittleEndian.PutUint32(cu[16:], uint32(editOffset))
+            resized = true
+            logPrintf("压缩 PowerPoint 编辑记录: 删除 %d 个旧版本", len(edits)-1)
+        }
+    }
+    return resized, f.writeStream(cuEntry, cu)
+}
+
+func inspectPowerPointBinary(f *cfbFile) {
+    cuEntry := f.Root.find(pptCurrentUserStream)
+    doc := f.Root.find(pptDocumentStream)
+    if cuEntry == nil || doc == nil {
+        return
+    }
+    cu := cuEntry.Data
+    ansi, _, ok := currentUserName(cu)
+    if !ok {
+        return
+    }
+    if name := strings.TrimSpace(string(cu[ansi[0]:ansi[1]])); name != "" {
+        reportPrintf("  当前用户: %s", name)
+    }
+    if binary.LittleEndian.Uint32(cu[12:]) != pptHeaderToken {
+        reportPrintf("  演示文稿已加密")
+        return
+    }
+    edits, err := pptUserEdits(doc.Data, int(binary.LittleEndian.Uint32(cu[16:])))
+    if err == nil && len(edits) > 1 {
+        reportPrintf("  快速保存历史: %d 个旧版本", len(edits)-1)
+    }
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+    "bytes"
+    "encoding/binary"
+    "testing"
+)
+
+func pptAtom(typ uint16, body []byte) []byte {
+    b := binary.LittleEndian.AppendUint16(nil, 0)
+    b = binary.LittleEndian.AppendUint16(b, typ)
+    b = binary.LittleEndian.AppendUint32(b, uint32(len(body)))
+    return append(b, body...)
+}
+
+func pptContainer(typ uint16, children ...[]byte) []byte {
+    b := pptAtom(typ, bytes.Join(children, nil))
+    b[0] = 0x0F
+    return b
+}
+
+func le32(v ...uint32) []byte {
+    var b []byte
+    for _, x := range v {
+        b = binary.LittleEndian.AppendUint32(b, x)
+    }
+    return b
+}
+
+// pptDocument 生成文档容器，参数为备注母版、母版和幻灯片的持久化编号
+func pptDocument(notesMaster, master uint32, slides ...uint32) []byte {
+    docAtom := make([]byte, 40)
+    binary.LittleEndian.PutUint32(docAtom[24:], notesMaster)
+    masters := pptContainer(0x0FF0, pptAtom(0x03F3, le32(master, 0, 0, 0x80000000, 0)))
+    masters[0] = 0x1F // instance 1: 母版列表
+    var persists [][]byte
+    for _, id := range slides {
+        persists = append(persists, pptAtom(0x03F3, le32(id, 0, 0, 256+id, 0)))
+    }
+    return pptContainer(0x03E8, pptAtom(0x03E9, docAtom), masters, pptContainer(0x0FF0, persists...))
+}
+
+func pptSlide(text string) []byte {
+    return pptContainer(0x03EE, pptAtom(0x0FA8, []byte(text)))
+}
+
+func pptEditAtom(last, dir uint32, encrypted bool) []byte {
+    body := le32(256, 0x03000000, last, dir, 1, 10, 1)
+    if encrypted {
+        body = append(body, le32(9)...)
+    }
+    return pptAtom(pptUserEditAtom, body)
+}
+
+// pptTestStream 生成有两次快速保存的演示文稿流：第二次保存修改了幻灯片 3 并删除了幻灯片 4，
+// 返回流和当前编辑记录的位置
+func pptTestStream(encrypted bool) ([]byte, int) {
+    var s []byte
+    add := func(rec []byte) uint32 {
+        pos := len(s)
+        s = append(s, rec...)
+        return uint32(pos)
+    }
+    add(pptAtom(0x0FF6, nil)) // 占位，使对象不从 0 开始
+    doc1 := add(pptDocument(5, 2, 3, 4))
+    master := add(pptContainer(0x03F8, pptAtom(0x0FA8, []byte("master"))))
+    slide3 := add(pptSlide("first draft"))
+    slide4 := add(pptSlide("OLD-SLIDE-SECRET"))
+    notes := add(pptContainer(0x03F0))
+    dir1 := add(pptAtom(pptPersistDirectory, le32(1|5<<20, doc1, master, slide3, slide4, notes)))
+    edit1 := add(pptEditAtom(0, dir1, false))
+
+    doc2 := add(pptDocument(5, 2, 3))
+    slide3b := add(pptSlide("final text"))
+    dir2 := add(pptAtom(pptPersistDirectory, le32(1|1<<20, doc2, 3|1<<20, slide3b)))
+    edit2 := add(pptEditAtom(edit1, dir2, encrypted))
+    return s, int(edit2)
+}
+
+func pptCurrentUser(offset int, name string) []byte {
+    body := le32(20, pptHeaderToken, uint32(offset))
+    body = binary.LittleEndian.AppendUint16(body, uint16(len(name)))
+    body = append(body, 0xF4, 0x03, 3, 0, 0, 0)
+    body = append(body, name...)
+    body = append(body, le32(8)...)
+    for _, c := range name {
+        body = append(body, byte(c), 0)
+    }
+    return pptAtom(pptCurrentUserAtom, body)
+}
+
+func TestCompactPowerPoint(t *testing.T) {
+    data, current := pptTestStream(false)
+    oldEdits, err := pptUserEdits(data, current)
+    if err != nil {
+        t.Fatal(err)
+    }
+    oldOffsets, err := pptPersistOffsets(data, oldEdits)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    root := &cfbEntry{Name: "Root Entry", Type: cfbTypeRoot, Children: []*cfbEntry{
+        {Name: pptCurrentUserStream, Type: cfbTypeStream, Data: pptCurrentUser(current, "Zhang San")},
+        {Name: pptDocumentStream, Type: cfbTypeStream, Data: data},
+    }}
+    f, err := readCFB((&cfbFile{Root: root}).bytes())
+    if err != nil {
+        t.Fatal(err)
+    }
+    resized, err := cleanPowerPointBinary(f)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !resized {
+        t.Fatal("没有压缩编辑记录")
+    }
+    f, err = readCFB(f.bytes())
+    if err != nil {
+        t.Fatal(err)
+    }
+    out := f.Root.find(pptDocumentStream).Data
+    cu := f.Root.find(pptCurrentUserStream).Data
+
+    if bytes.Contains(out, []byte("OLD-SLIDE-SECRET")) || bytes.Contains(out, []byte("first draft")) {
+        t.Error("旧版本幻灯片仍在流中")
+    }
+    if bytes.Contains(cu, []byte("Zhang")) {
+        t.Error("当前用户名没有清空")
+    }
+
+    edits, err := pptUserEdits(out, int(binary.LittleEndian.Uint32(cu[16:])))
+    if err != nil {
+        t.Fatalf("Current User 没有指向有效的编辑记录: %v", err)
+    }
+    if len(edits) != 1 || binary.LittleEndian.Uint32(edits[0].body[8:]) != 0 {
+        t.Fatalf("剩余 %d 个编辑记录", len(edits))
+    }
+    dir := int(binary.LittleEndian.Uint32(edits[0].body[12:]))
+    if typ, _, ok := pptRecord(out, dir); !ok || typ != pptPersistDirectory {
+        t.Fatal("offsetPersistDirectory 没有指向持久化目录")
+    }
+
+    offsets, err := pptPersistOffsets(out, edits)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(offsets) != 4 {
+        t.Errorf("保留了 %d 个对象，应为 4 个", len(offsets))
+    }
+    if _, ok := offsets[4]; ok {
+        t.Error("已删除的幻灯片仍在持久化目录中")
+    }
+    for id, offset := range offsets {
+        typ, n, ok := pptRecord(out, offset)
+        oldTyp, oldN, _ := pptRecord(data, oldOffsets[id])
+        if !ok || typ != oldTyp || !bytes.Equal(out[offset:offset+n], data[oldOffsets[id]:oldOffsets[id]+oldN]) {
+            t.Errorf("持久化对象 %d 与原始记录不一致", id)
+        }
+    }
+}
+
+func TestCompactPowerPointEncrypted(t *testing.T) {
+    data, current := pptTestStream(true)
+    if _, _, err := compactPowerPoint(data, current); err == nil {
+        t.Error("加密的编辑记录应拒绝压缩")
+    }
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+    "crypto/rand"
+    "crypto/sha512"
+    "encoding/base64"
+    "encoding/binary"
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+    "unicode/utf16"
+)
+
+const protectSpinCount = 100000
+
+// Word 旧版密码密钥算法的初始码和加密矩阵，见 MS-OI29500 2.1.1699
+var (
+    wordInitialCodes = [15]uint16{
+        0xE1F0, 0x1D0F, 0xCC9C, 0x84C0, 0x110C, 0x0E10, 0xF1CE, 0x313E,
+        0x1872, 0xE139, 0xD40F, 0x84F9, 0x280C, 0xA96A, 0x4EC3,
+    }
+    wordEncryptionMatrix = [15][7]uint16{
+        {0xAEFC, 0x4DD9, 0x9BB2, 0x2745, 0x4E8A, 0x9D14, 0x2A09},
+        {0x7B61, 0xF6C2, 0xFDA5, 0xEB6B, 0xC6F7, 0x9DCF, 0x2BBF},
+        {0x4563, 0x8AC6, 0x05AD, 0x0B5A, 0x16B4, 0x2D68, 0x5AD0},
+        {0x0375, 0x06EA, 0x0DD4, 0x1BA8, 0x3750, 0x6EA0, 0xDD40},
+        {0xD849, 0xA0B3, 0x5147, 0xA28E, 0x553D, 0xAA7A, 0x44D5},
+        {0x6F45, 0xDE8A, 0xAD35, 0x4A4B, 0x9496, 0x390D, 0x721A},
+        {0xEB23, 0xC667, 0x9CEF, 0x29FF, 0x53FE, 0xA7FC, 0x5FD9},
+        {0x47D3, 0x8FA6, 0x0F6D, 0x1EDA, 0x3DB4, 0x7B68, 0xF6D0},
+        {0xB861, 0x60E3, 0xC1C6, 0x93AD, 0x377B, 0x6EF6, 0xDDEC},
+        {0x45A0, 0x8B40, 0x06A1, 0x0D42, 0x1A84, 0x3508, 0x6A10},
+        {0xAA51, 0x4483, 0x8906, 0x022D, 0x045A, 0x08B4, 0x1168},
+        {0x76B4, 0xED68, 0xCAF1, 0x85C3, 0x1BA7, 0x374E, 0x6E9C},
+        {0x3730, 0x6E60, 0xDCC0, 0xA9A1, 0x4363, 0x86C6, 0x1DAD},
+        {0x3331, 0x6662, 0xCCC4, 0x89A9, 0x0373, 0x06E6, 0x0DCC},
+        {0x1021, 0x2042, 0x4084, 0x8108, 0x1231, 0x2462, 0x48C4},
+    }
+)
+
+var (
+    wordProtectTagRe  = regexp.MustCompile(`<w:(?:documentProtection|writeProtection)\b[^>]*>`)
+    excelProtectTagRe = regexp.MustCompile(`<(?:fileSharing|workbookProtection|sheetProtection|protectedRange)\b[^>]*>`)
+    tagNameRe         = regexp.MustCompile(`^<([\w:]+)`)
+)
+
+// 各保护元素中保存密码哈希的属性前缀，如 workbookHashValue、revisionsHashValue
+var protectHashAttrs = []string{
+    "password", "hash", "salt", "cryptProviderType", "cryptAlgorithmClass", "cryptAlgorithmType",
+    "cryptAlgorithmSid", "cryptSpinCount", "cryptProvider", "algIdExt", "algIdExtSource",
+    "cryptProviderTypeExt", "cryptProviderTypeExtSource",
+    "reservationPassword", "algorithmName", "hashValue", "saltValue", "spinCount",
+}
+
+func tagName(tag string) string {
+    if m := tagNameRe.FindStringSubmatch(tag); m != nil {
+        return m[1]
+    }
+    return ""
+}
+
+// protectAttrNames 返回带前缀的哈希属性名，Excel 工作簿保护使用 workbook/revisions 前缀
+func protectAttrNames(prefix string) []string {
+    var names []string
+    for _, a := range protectHashAttrs {
+        switch {
+        case prefix == "":
+            names = append(names, a)
+        case strings.HasSuffix(prefix, ":"):
+            names = append(names, prefix+a)
+        default:
+            names = append(names, prefix+strings.ToUpper(a[:1])+a[1:])
+        }
+    }
+    return names
+}
+
+func hasProtectHash(tag, prefix string) bool {
+    for _, a := range protectAttrNames(prefix) {
+        if xmlAttr(tag, a) != "" {
+            return true
+        }
+    }
+    return false
+}
+
+func stripProtectHash(tag, prefix string) string {
+    for _, a := range protectAttrNames(prefix) {
+        tag = removeXMLAttr(tag, a)
+    }
+    return tag
+}
+
+// protectHash 按 ECMA-376 的 SHA-512 迭代算法生成密码哈希
+func protectHash(password string, salt []byte) []byte {
+    var pw []byte
+    for _, u := range utf16.Encode([]rune(password)) {
+        pw = binary.LittleEndian.AppendUint16(pw, u)
+    }
+    h := sha512.Sum512(append(append([]byte(nil), salt...), pw...))
+    hash := h[:]
+    for i := uint32(0); i < protectSpinCount; i++ {
+        h = sha512.Sum512(binary.LittleEndian.AppendUint32(append([]byte(nil), hash...), i))
+        hash = h[:]
+    }
+    return hash
+}
+
+// wordLegacyKey 计算 Word 旧版 32 位密码密钥: 高 16 位由加密矩阵得到，低 16 位为校验值
+func wordLegacyKey(password string) uint32 {
+    runes := []rune(password)
+    if len(runes) > 15 {
+        runes = runes[:15]
+    }
+    if len(runes) == 0 {
+        return 0
+    }
+    // 每个字符取低字节，低字节为 0 时取高字节
+    chars := make([]byte, len(runes))
+    for i, r := range runes {
+        chars[i] = byte(r)
+        if chars[i] == 0 {
+            chars[i] = byte(r >> 8)
+        }
+    }
+    n := len(chars)
+    high := wordInitialCodes[n-1]
+    for i, c := range chars {
+        for bit := 0; bit < 7; bit++ {
+            if c&(1<<bit) != 0 {
+                high ^= wordEncryptionMatrix[15-n+i][bit]
+            }
+        }
+    }
+    rotate := func(v uint16) uint16 { return (v>>14)&1 | (v<<1)&0x7FFF }
+    var low uint16
+    for i := n - 1; i >= 0; i-- {
+        low = rotate(low) ^ uint16(chars[i])
+    }
+    low = rotate(low) ^ uint16(n) ^ 0xCE4B
+    return uint32(high)<<16 | uint32(low)
+}
+
+// wordProtectInput 返回 Word 文档保护实际参与 SHA 哈希的字符串:
+// 旧版密钥按字节倒序写成的大写十六进制，如 "Example" 的 0x64CEED7E 为 "7EEDCE64"
+func wordProtectInput(password string) string {
+    key := wordLegacyKey(password)
+    return fmt.Sprintf("%02X%02X%02X%02X", byte(key), byte(key>>8), byte(key>>16), byte(key>>24))
+}
+
+func setProtectHash(tag, prefix, password string) (string, error) {
+    salt := make([]byte, 16)
+    if deterministic {
+        // 固定输出模式下由原标签和新密码派生盐，保证相同输入得到相同结果
+        h := sha512.Sum512([]byte(tag + password))
+        copy(salt, h[:])
+    } else if _, err := rand.Read(salt); err != nil {
+        return tag, err
+    }
+    // Word 先把密码转换为旧版密钥再做迭代哈希，Excel 直接使用密码
+    if prefix == "w:" {
+        password = wordProtectInput(password)
+    }
+    names := protectAttrNames(prefix)
+    n := len(names)
+    tag = setXMLAttr(tag, names[n-4], "SHA-512")
+    tag = setXMLAttr(tag, names[n-3], base64.StdEncoding.EncodeToString(protectHash(password, salt)))
+    tag = setXMLAttr(tag, names[n-2], base64.StdEncoding.EncodeToString(salt))
+    tag = setXMLAttr(tag, names[n-1], fmt.Sprint(protectSpinCount))
+    return tag, nil
+}
+
+// protectPrefixes 返回元素上各组密码属性的前缀
+func protectPrefixes(name string) []string {
+    switch name {
+    case "w:documentProtection", "w:writeProtection":
+        return []string{"w:"}
+    case "workbookProtection":
+        return []string{"workbook", "revisions"}
+    }
+    return []string{""}
+}
+
+// cleanProtection 处理文档、工作簿和工作表保护中的密码哈希及保留用户名
+// remove: 删除保护元素(受保护区域只去掉密码)
+// reset: 保留保护设置，用新密码重新生成哈希和盐
+func cleanProtection(dir, policy, password string) error {
+    var parts, elements []string
+    var re *regexp.Regexp
+    switch {
+    case strings.HasPrefix(mainDocumentPart(dir), "word/"):
+        parts, re = []string{"word/settings.xml"}, wordProtectTagRe
+        elements = []string{"w:documentProtection", "w:writeProtection"}
+    case workbookPart(dir) != "":
+        parts, re = append([]string{workbookPart(dir)}, worksheetParts(dir)...), excelProtectTagRe
+        elements = []string{"fileSharing", "workbookProtection", "sheetProtection"}
+    default:
+        return nil
+    }
+
+    for _, p := range parts {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        if policy == "remove" {
+            // 按整个元素删除，非自闭合元素不会留下孤立的结束标签
+            var spans [][2]int
+            for _, name := range elements {
+                for _, s := range xmlElementSpans(text, name) {
+                    if user := xmlAttr(text[s[0]:s[1]], "userName"); name == "fileSharing" && user != "" {
+                        logPrintf("删除文件保留用户名: %s", xmlUnescape(user))
+                    }
+                    logPrintf("删除保护: %s %s", p, name)
+                    spans = append(spans, s)
+                }
+            }
+            sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+            text = removeSpans(text, spans)
+        }
+        var failed error
+        text = re.ReplaceAllStringFunc(text, func(tag string) string {
+            name := tagName(tag)
+            if name == "fileSharing" && xmlAttr(tag, "userName") != "" {
+                logPrintf("删除文件保留用户名: %s", xmlUnescape(xmlAttr(tag, "userName")))
+                tag = removeXMLAttr(tag, "userName")
+            }
+            if policy == "remove" {
+                // 其余保护元素已整体删除，这里只剩受保护区域
+                for _, prefix := range protectPrefixes(name) {
+                    tag = stripProtectHash(tag, prefix)
+                }
+                return tag
+            }
+            for _, prefix := range protectPrefixes(name) {
+                if !hasProtectHash(tag, prefix) {
+                    continue
+                }
+                tag = stripProtectHash(tag, prefix)
+                if tag, failed = setProtectHash(tag, prefix, password); failed != nil {
+                    return tag
+                }
+                logPrintf("重置保护密码: %s %s", p, name)
+            }
+            return tag
+        })
+        if failed != nil {
+            return failed
+        }
+        if err := writePart(dir, p, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectProtection(dir string) {
+    var parts []string
+    var re *regexp.Regexp
+    switch {
+    case strings.HasPrefix(mainDocumentPart(dir), "word/"):
+        parts, re = []string{"word/settings.xml"}, wordProtectTagRe
+    case workbookPart(dir) != "":
+        parts, re = append([]string{workbookPart(dir)}, worksheetParts(dir)...), excelProtectTagRe
+    default:
+        return
+    }
+    for _, p := range parts {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        for _, tag := range re.FindAllString(string(data), -1) {
+            name := tagName(tag)
+            hashed := false
+            for _, prefix := range protectPrefixes(name) {
+                hashed = hashed || hasProtectHash(tag, prefix)
+            }
+            if hashed {
+                reportPrintf("  密码保护: %s %s (含密码哈希)", p, name)
+            }
+            if user := xmlAttr(tag, "userName"); user != "" {
+                reportPrintf("  文件保留用户: %s", xmlUnescape(user))
+            }
+        }
+    }
+}
+
+var (
+    permStartRe   = regexp.MustCompile(`<w:permStart\b[^>]*>`)
+    permEndRe     = regexp.MustCompile(`<w:permEnd\b[^>]*>`)
+    emptyRangesRe = regexp.MustCompile(`<protectedRanges\s*(?:/>|>\s*</protectedRanges>)`)
+    sidRe         = regexp.MustCompile(`S-1-\d+(?:-\d+)+`)
+)
+
+// cleanPermissions 处理 Word 区域编辑权限和 Excel 受保护区域中的账户信息
+// remove: 删除整个权限区域
+// anon: Word 去掉编辑者账户，保留按组授权的区域，只授权给单个账户的区域删除；Excel 删除安全描述符
+func cleanPermissions(dir, policy string) error {
+    if strings.HasPrefix(mainDocumentPart(dir), "word/") {
+        for _, p := range wordStoryParts(dir) {
+            data, err := readPart(dir, p)
+            if err != nil {
+                continue
+            }
+            text := string(data)
+            if !permStartRe.MatchString(text) {
+                continue
+            }
+            if policy == "remove" {
+                text = permEndRe.ReplaceAllString(permStartRe.ReplaceAllString(text, ""), "")
+                logPrintf("删除区域权限: %s", p)
+            } else {
+                // 只授权给单个账户的区域改成 everyone 会让所有人都能编辑，整个删除
+                dropped := map[string]bool{}
+                text = permStartRe.ReplaceAllStringFunc(text, func(tag string) string {
+                    ed := xmlAttr(tag, "w:ed")
+                    if ed == "" {
+                        return tag
+                    }
+                    if xmlAttr(tag, "w:edGrp") == "" {
+                        dropped[xmlAttr(tag, "w:id")] = true
+                        logPrintf("删除区域权限: %s 编辑者 %s", p, xmlUnescape(ed))
+                        return ""
+                    }
+                    return removeXMLAttr(tag, "w:ed")
+                })
+                text = permEndRe.ReplaceAllStringFunc(text, func(tag string) string {
+                    if dropped[xmlAttr(tag, "w:id")] {
+                        return ""
+                    }
+                    return tag
+                })
+                logPrintf("匿名化区域权限: %s", p)
+            }
+            if err := writePart(dir, p, []byte(text)); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    if workbookPart(dir) == "" {
+        return nil
+    }
+    for _, p := range worksheetParts(dir) {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        spans := xmlElementSpans(text, "protectedRange")
+        if len(spans) == 0 {
+            continue
+        }
+        if policy == "remove" {
+            text = emptyRangesRe.ReplaceAllString(removeSpans(text, spans), "")
+            logPrintf("删除受保护区域: %s 共 %d 个", p, len(spans))
+        } else {
+            text = removeSpans(text, xmlElementSpans(text, "securityDescriptor"))
+            text = excelProtectTagRe.ReplaceAllStringFunc(text, func(tag string) string {
+                if tagName(tag) != "protectedRange" {
+                    return tag
+                }
+                return removeXMLAttr(tag, "securityDescriptor")
+            })
+            logPrintf("删除受保护区域的安全描述符: %s", p)
+        }
+        if err := writePart(dir, p, []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func inspectPermissions(dir string) {
+    if strings.HasPrefix(mainDocumentPart(dir), "word/") {
+        for _, p := range wordStoryParts(dir) {
+            data, err := readPart(dir, p)
+            if err != nil {
+                continue
+            }
+            seen := map[string]bool{}
+            for _, tag := range permStartRe.FindAllString(string(data), -1) {
+                if ed := xmlUnescape(xmlAttr(tag, "w:ed")); ed != "" && !seen[ed] {
+                    seen[ed] = true
+                    reportPrintf("  区域权限: %s 编辑者 %s", p, ed)
+                }
+            }
+        }
+        return
+    }
+
+    if workbookPart(dir) == "" {
+        return
+    }
+    for _, p := range worksheetParts(dir) {
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        text := string(data)
+        for _, s := range xmlElementSpans(text, "protectedRange") {
+            elem := text[s[0]:s[1]]
+            sids := sidRe.FindAllString(elem, -1)
+            if len(sids) > 0 {
+                reportPrintf("  受保护区域: %s %s 账户 %s", p, xmlUnescape(xmlAttr(elem, "name")), strings.Join(sids, ", "))
+            }
+        }
+    }
+}
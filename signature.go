@@ -0,0 +1,129 @@
+package main
+
+import (
+    "crypto/x509"
+    "encoding/base64"
+    "errors"
+    "regexp"
+    "strings"
+)
+
+const (
+    relSignatureOrigin = "http://schemas.openxmlformats.org/package/2006/relationships/digital-signature/origin"
+    relVbaSignature    = "http://schemas.microsoft.com/office/2006/relationships/vbaProjectSignature"
+    relVbaSignatureAg  = "http://schemas.microsoft.com/office/2014/relationships/vbaProjectSignatureAgile"
+    relVbaSignatureV3  = "http://schemas.microsoft.com/office/2020/relationships/vbaProjectSignatureV3"
+)
+
+var errSignedSkipped = errors.New("文件已数字签名，按策略跳过")
+
+var (
+    x509CertRe    = regexp.MustCompile(`(?s)<(?:\w+:)?X509Certificate>(.*?)</(?:\w+:)?X509Certificate>`)
+    x509SubjectRe = regexp.MustCompile(`(?s)<(?:\w+:)?X509SubjectName>(.*?)</(?:\w+:)?X509SubjectName>`)
+)
+
+func isSignaturePart(name string) bool {
+    return strings.HasPrefix(strings.ToLower(name), "_xmlsignatures/")
+}
+
+// isSignedPackage 判断包是否带有 XML 数字签名
+func isSignedPackage(dir string) bool {
+    for _, r := range readRels(dir, "_rels/.rels") {
+        if r.Type == relSignatureOrigin {
+            return true
+        }
+    }
+    for _, p := range listParts(dir) {
+        if isSignaturePart(p) {
+            return true
+        }
+    }
+    return false
+}
+
+// signerSubjects 返回所有签名证书的主题
+func signerSubjects(dir string) []string {
+    var subjects []string
+    for _, p := range listParts(dir) {
+        if !isSignaturePart(p) || !strings.HasSuffix(strings.ToLower(p), ".xml") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        subject := ""
+        if m := x509CertRe.FindSubmatch(data); m != nil {
+            der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(m[1])), ""))
+            if err == nil {
+                if cert, err := x509.ParseCertificate(der); err == nil {
+                    subject = cert.Subject.String()
+                }
+            }
+        }
+        if subject == "" {
+            if m := x509SubjectRe.FindSubmatch(data); m != nil {
+                subject = strings.TrimSpace(string(m[1]))
+            }
+        }
+        if subject == "" {
+            subject = "(未知签名者)"
+        }
+        subjects = append(subjects, p+": "+subject)
+    }
+    return subjects
+}
+
+// removeSignatures 删除签名来源部件、签名部件及包级 origin 关系
+func removeSignatures(dir string) error {
+    for _, r := range readRels(dir, "_rels/.rels") {
+        if r.Type == relSignatureOrigin && !r.external() {
+            target := resolveTarget("", r.Target)
+            logPrintf("删除数字签名: %s", target)
+            if err := removePartTree(dir, target); err != nil {
+                return err
+            }
+        }
+    }
+    for _, p := range listParts(dir) {
+        if isSignaturePart(p) {
+            if err := removePart(dir, p); err != nil {
+                return err
+            }
+        }
+    }
+    _, err := removeRels(dir, "_rels/.rels", func(r relationship) bool {
+        return r.Type == relSignatureOrigin
+    })
+    return err
+}
+
+// removeVbaSignatures 删除 VBA 工程签名，工程内容改动后签名已失效
+func removeVbaSignatures(dir, vbaProject string) error {
+    for _, relType := range []string{relVbaSignature, relVbaSignatureAg, relVbaSignatureV3} {
+        for _, target := range findRelsByType(dir, vbaProject, relType) {
+            logPrintf("删除VBA签名: %s", target)
+            if err := removePart(dir, target); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func inspectSignatures(dir string) {
+    if !isSignedPackage(dir) {
+        return
+    }
+    reportPrintf("  数字签名: 已签名，清理后签名将失效")
+    for _, s := range signerSubjects(dir) {
+        reportPrintf("    签名者 %s", s)
+    }
+    for _, target := range findRelsByType(dir, mainDocumentPart(dir), relVbaProject) {
+        for _, relType := range []string{relVbaSignature, relVbaSignatureAg, relVbaSignatureV3} {
+            for _, sig := range findRelsByType(dir, target, relType) {
+                reportPrintf("    VBA签名 %s", sig)
+            }
+        }
+    }
+}
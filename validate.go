@@ -0,0 +1,176 @@
+package main
+
+import (
+    "bytes"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+)
+
+var errInvalidPackage = errors.New("清理后的文件未通过包结构校验，已保留原文件")
+
+// pruneMissingParts 删除指向不存在部件的内部关系和内容类型声明
+// (docProps、customXml 在解压时被跳过，其关系需要一并清除)
+func pruneMissingParts(dir string) error {
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        if source != "" && !partExists(dir, source) {
+            continue
+        }
+        removed, err := removeRels(dir, rels, func(r relationship) bool {
+            return !r.external() && !partExists(dir, resolveTarget(source, r.Target))
+        })
+        if err != nil {
+            return err
+        }
+        for _, r := range removed {
+            logPrintf("删除指向不存在部件的关系: %s %s", rels, r.Target)
+        }
+    }
+
+    data, err := readPart(dir, contentTypesPart)
+    if err != nil {
+        return nil
+    }
+    for _, o := range overrideRe.FindAllString(string(data), -1) {
+        name := strings.TrimPrefix(xmlAttr(o, "PartName"), "/")
+        if !partExists(dir, name) {
+            if err := removeContentTypeOverride(dir, name); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+func isWellFormedXML(data []byte) error {
+    d := xml.NewDecoder(bytes.NewReader(data))
+    d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+        return input, nil
+    }
+    for {
+        _, err := d.Token()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+    }
+}
+
+// validatePackage 检查 OPC 包结构，返回发现的问题
+func validatePackage(dir string) []string {
+    var problems []string
+    add := func(format string, args ...interface{}) {
+        problems = append(problems, fmt.Sprintf(format, args...))
+    }
+
+    if _, err := readPart(dir, contentTypesPart); err != nil {
+        add("缺少 %s", contentTypesPart)
+        return problems
+    }
+    main := mainDocumentPart(dir)
+    if main == "" || !partExists(dir, main) {
+        add("缺少主文档部件")
+    }
+
+    declared := map[string]map[string]bool{}
+    for _, rels := range listRelsParts(dir) {
+        source := sourceOfRels(rels)
+        ids := map[string]bool{}
+        for _, r := range readRels(dir, rels) {
+            ids[r.ID] = true
+            if !r.external() && !partExists(dir, resolveTarget(source, r.Target)) {
+                add("%s: 关系 %s 的目标不存在 %s", rels, r.ID, r.Target)
+            }
+        }
+        declared[source] = ids
+    }
+
+    for _, p := range listParts(dir) {
+        if p == contentTypesPart {
+            continue
+        }
+        if contentTypeOf(dir, p) == "" {
+            add("%s: 没有内容类型声明", p)
+        }
+        if !strings.HasSuffix(p, ".xml") && !strings.HasSuffix(p, ".rels") && !strings.HasSuffix(p, ".vml") {
+            continue
+        }
+        data, err := readPart(dir, p)
+        if err != nil {
+            continue
+        }
+        if err := isWellFormedXML(data); err != nil {
+            add("%s: XML 格式错误 %v", p, err)
+            continue
+        }
+        if strings.HasSuffix(p, ".rels") {
+            continue
+        }
+        for _, m := range relIDRe.FindAllStringSubmatch(string(data), -1) {
+            if m[1] != "" && !declared[p][m[1]] {
+                add("%s: 引用了未声明的关系 %s", p, m[1])
+            }
+        }
+    }
+    return problems
+}
+
+// problemKey 去掉 XML 错误的行号等细节，原本就格式错误的部件清理后报错位置可能变化
+func problemKey(p string) string {
+    if i := strings.Index(p, "XML 格式错误"); i >= 0 {
+        return p[:i+len("XML 格式错误")]
+    }
+    return p
+}
+
+// newProblems 返回 after 中不在 before 里的问题
+func newProblems(before, after []string) []string {
+    seen := map[string]bool{}
+    for _, p := range before {
+        seen[problemKey(p)] = true
+    }
+    var added []string
+    for _, p := range after {
+        if !seen[problemKey(p)] {
+            added = append(added, p)
+        }
+    }
+    return added
+}
+
+// checkFile 校验文件的包结构，不修改文件
+func checkFile(filePath string) error {
+    reportPrintf("文件: %s", filePath)
+    if !isZipFile(filePath) {
+        reportPrintf("  非OOXML格式，跳过检查")
+        return nil
+    }
+
+    dir, err := os.MkdirTemp("", "cleanmeta")
+    if err != nil {
+        return err
+    }
+    defer os.RemoveAll(dir)
+
+    if err := extractPackage(filePath, dir, nil); err != nil {
+        return err
+    }
+    validate := validatePackage
+    if isODFPackage(dir) {
+        validate = validateODF
+    }
+    problems := validate(dir)
+    for _, p := range problems {
+        reportPrintf("  %s", p)
+    }
+    if len(problems) == 0 {
+        reportPrintf("  包结构正常")
+    }
+    return nil
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "regexp"
+    "strings"
+    "unicode/utf16"
+)
+
+// VBA 工程清理，参考 MS-OVBA
+
+// dir 流记录 ID
+const (
+    vbaProjectCodePage  = 0x0003
+    vbaProjectDocString = 0x0005
+    vbaProjectHelpFile  = 0x0006
+    vbaProjectVersion   = 0x0009
+    vbaRefRegistered    = 0x000D
+    vbaRefProject       = 0x000E
+    vbaDirTerminator    = 0x0010
+    vbaModuleStreamName = 0x001A
+    vbaModuleDocString  = 0x001C
+    vbaRefControl       = 0x002F
+    vbaRefControlExt    = 0x0030
+    vbaModuleOffset     = 0x0031
+    vbaModuleStreamUni  = 0x0032
+    vbaRefOriginal      = 0x0033
+    vbaHelpFile2        = 0x003D
+    vbaDocStringUnicode = 0x0040
+    vbaModuleDocUnicode = 0x0048
+)
+
+type vbaRecord struct {
+    ID   uint16
+    Data []byte
+}
+
+// 多字节代码页，路径中 0x5C 可能是双字节字符的尾字节
+var vbaDBCSCodePages = map[uint16]bool{932: true, 936: true, 949: true, 950: true}
+
+var (
+    projectIDRe       = regexp.MustCompile(`(?m)^ID="\{[0-9A-Fa-f-]+\}"`)
+    projectHelpFileRe = regexp.MustCompile(`(?m)^HelpFile="[^"\r\n]*"`)
+    projectWorkspace  = regexp.MustCompile(`(?s)\[Workspace\]\r?\n.*?(\r?\n\r?\n|\r?\n\[|$)`)
+)
+
+// cleanVbaProjects 清理包内所有 VBA 工程的元数据
+func cleanVbaProjects(dir string) error {
+    main := mainDocumentPart(dir)
+    if main == "" {
+        return nil
+    }
+    for _, target := range findRelsByType(dir, main, relVbaProject) {
+        data, err := readPart(dir, target)
+        if err != nil {
+            continue
+        }
+        cleaned, err := cleanVbaProject(data)
+        if err != nil {
+            return fmt.Errorf("%s: %v", target, err)
+        }
+        if err := writePart(dir, target, cleaned); err != nil {
+            return err
+        }
+        if err := removeVbaSignatures(dir, target); err != nil {
+            return err
+        }
+        logPrintf("清理VBA工程: %s", target)
+    }
+    return nil
+}
+
+// cleanVbaProject 清除工程 GUID、帮助文件、引用库路径和 p-code 缓存，保留宏源码
+func cleanVbaProject(data []byte) ([]byte, error) {
+    f, err := readCFB(data)
+    if err != nil {
+        return nil, err
+    }
+    vba := f.Root.find("VBA")
+    dirStream := f.Root.find("VBA/dir")
+    if vba == nil || dirStream == nil {
+        return nil, fmt.Errorf("缺少 VBA/dir 流")
+    }
+
+    raw, err := vbaDecompress(dirStream.Data)
+    if err != nil {
+        return nil, err
+    }
+    records, err := parseVbaDir(raw)
+    if err != nil {
+        return nil, err
+    }
+
+    codePage := uint16(1252)
+    for _, r := range records {
+        if r.ID == vbaProjectCodePage && len(r.Data) >= 2 {
+            codePage = binary.LittleEndian.Uint16(r.Data)
+        }
+    }
+    dbcs := vbaDBCSCodePages[codePage]
+
+    // 去掉模块流开头的性能缓存，并把 TextOffset 置 0
+    var streamName string
+    for i, r := range records {
+        switch r.ID {
+        case vbaModuleStreamName:
+            streamName = string(r.Data)
+        case vbaModuleStreamUni:
+            streamName = decodeUTF16LE(r.Data)
+        case vbaModuleOffset:
+            if len(r.Data) < 4 {
+                continue
+            }
+            offset := binary.LittleEndian.Uint32(r.Data)
+            if m := vba.find(streamName); m != nil && int(offset) <= len(m.Data) {
+                m.Data = m.Data[offset:]
+                records[i].Data = make([]byte, 4)
+            }
+        case vbaProjectDocString, vbaDocStringUnicode, vbaModuleDocString, vbaModuleDocUnicode,
+            vbaProjectHelpFile, vbaHelpFile2:
+            records[i].Data = nil
+        case vbaRefRegistered, vbaRefControl, vbaRefControlExt:
+            records[i].Data = rewriteVbaLibid(r.Data, 0, dbcs)
+        case vbaRefProject:
+            d := rewriteVbaLibid(r.Data, 0, dbcs)
+            if len(d) >= 4 {
+                d = rewriteVbaLibid(d, 4+int(binary.LittleEndian.Uint32(d)), dbcs)
+            }
+            records[i].Data = d
+        case vbaRefOriginal:
+            records[i].Data = []byte(cleanLibid(string(r.Data), dbcs))
+        }
+    }
+    dirStream.Data = vbaCompress(buildVbaDir(records))
+
+    // _VBA_PROJECT 只保留头部，Office 打开时会从源码重新编译
+    if p := vba.find("_VBA_PROJECT"); p != nil {
+        p.Data = []byte{0xCC, 0x61, 0xFF, 0xFF, 0x00, 0x00, 0x00}
+    }
+    for _, c := range append([]*cfbEntry(nil), vba.Children...) {
+        if strings.HasPrefix(strings.ToUpper(c.Name), "__SRP_") {
+            vba.remove(c)
+        }
+    }
+
+    if p := f.Root.find("PROJECT"); p != nil {
+        text := string(p.Data)
+        text = projectIDRe.ReplaceAllString(text, `ID="{00000000-0000-0000-0000-000000000000}"`)
+        text = projectHelpFileRe.ReplaceAllString(text, `HelpFile=""`)
+        text = projectWorkspace.ReplaceAllStringFunc(text, func(s string) string {
+            if strings.HasSuffix(s, "[") {
+                return "["
+            }
+            return ""
+        })
+        p.Data = []byte(text)
+    }
+
+    return f.bytes(), nil
+}
+
+func parseVbaDir(data []byte) ([]vbaRecord, error) {
+    var records []vbaRecord
+    for pos := 0; pos < len(data); {
+        if pos+6 > len(data) {
+            return nil, fmt.Errorf("dir 流记录截断")
+        }
+        id := binary.LittleEndian.Uint16(data[pos:])
+        size := int(binary.LittleEndian.Uint32(data[pos+2:]))
+        pos += 6
+        // PROJECTVERSION 的 Size 字段固定为 4，实际数据为 6 字节
+        if id == vbaProjectVersion {
+            size = 6
+        }
+        if pos+size > len(data) {
+            return nil, fmt.Errorf("dir 流记录长度无效: 0x%04X", id)
+        }
+        records = append(records, vbaRecord{ID: id, Data: data[pos : pos+size]})
+        pos += size
+        // 结束记录之后可能是未压缩块补的 0，忽略
+        if id == vbaDirTerminator {
+            break
+        }
+    }
+    return records, nil
+}
+
+func buildVbaDir(records []vbaRecord) []byte {
+    var buf bytes.Buffer
+    for _, r := range records {
+        binary.Write(&buf, binary.LittleEndian, r.ID)
+        size := uint32(len(r.Data))
+        if r.ID == vbaProjectVersion {
+            size = 4
+        }
+        binary.Write(&buf, binary.LittleEndian, size)
+        buf.Write(r.Data)
+    }
+    return buf.Bytes()
+}
+
+// rewriteVbaLibid 处理 data[pos:] 处以 4 字节长度开头的 Libid 字符串
+func rewriteVbaLibid(data []byte, pos int, dbcs bool) []byte {
+    if pos+4 > len(data) {
+        return data
+    }
+    n := int(binary.LittleEndian.Uint32(data[pos:]))
+    if pos+4+n > len(data) {
+        return data
+    }
+    libid := []byte(cleanLibid(string(data[pos+4:pos+4+n]), dbcs))
+    out := append([]byte(nil), data[:pos]...)
+    out = binary.LittleEndian.AppendUint32(out, uint32(len(libid)))
+    out = append(out, libid...)
+    return append(out, data[pos+4+n:]...)
+}
+
+// cleanLibid 只保留引用库的文件名，去掉目录
+// 格式: *\G{GUID}#版本#LCID#路径#说明 或 *\C路径
+func cleanLibid(libid string, dbcs bool) string {
+    switch {
+    case strings.HasPrefix(libid, `*\G`), strings.HasPrefix(libid, `*\H`):
+        parts := strings.Split(libid, "#")
+        if len(parts) >= 4 {
+            parts[3] = baseNameMBCS(parts[3], dbcs)
+        }
+        return strings.Join(parts, "#")
+    case strings.HasPrefix(libid, `*\C`), strings.HasPrefix(libid, `*\D`):
+        return libid[:3] + baseNameMBCS(libid[3:], dbcs)
+    }
+    return libid
+}
+
+func decodeUTF16LE(b []byte) string {
+    units := make([]uint16, len(b)/2)
+    for i := range units {
+        units[i] = binary.LittleEndian.Uint16(b[i*2:])
+    }
+    return string(utf16.Decode(units))
+}
+
+func baseNameMBCS(p string, dbcs bool) string {
+    cut := 0
+    for i := 0; i < len(p); i++ {
+        c := p[i]
+        if dbcs && c >= 0x81 && c <= 0xFE {
+            i++
+            continue
+        }
+        if c == '\\' || c == '/' || c == ':' {
+            cut = i + 1
+        }
+    }
+    return p[cut:]
+}
+
+// vbaDecompress 解压 MS-OVBA 压缩容器
+func vbaDecompress(data []byte) ([]byte, error) {
+    if len(data) == 0 || data[0] != 0x01 {
+        return nil, fmt.Errorf("VBA 压缩签名无效")
+    }
+    var out []byte
+    pos := 1
+    for pos+2 <= len(data) {
+        header := binary.LittleEndian.Uint16(data[pos:])
+        end := pos + int(header&0x0FFF) + 3
+        if end > len(data) {
+            end = len(data)
+        }
+        pos += 2
+        if header&0x8000 == 0 {
+            if pos+4096 > len(data) {
+                return nil, fmt.Errorf("VBA 未压缩块截断")
+            }
+            out = append(out, data[pos:pos+4096]...)
+            pos += 4096
+            continue
+        }
+        start := len(out)
+        for pos < end {
+            flags := data[pos]
+            pos++
+            for bit := 0; bit < 8 && pos < end; bit++ {
+                if flags&(1<<bit) == 0 {
+                    out = append(out, data[pos])
+                    pos++
+                    continue
+                }
+                if pos+2 > end {
+                    return nil, fmt.Errorf("VBA 复制标记截断")
+                }
+                token := binary.LittleEndian.Uint16(data[pos:])
+                pos += 2
+                bitCount := vbaCopyTokenBits(len(out) - start)
+                lengthMask := uint16(0xFFFF >> bitCount)
+                length := int(token&lengthMask) + 3
+                offset := int(token>>(16-bitCount)) + 1
+                src := len(out) - offset
+                if src < start {
+                    return nil, fmt.Errorf("VBA 复制标记越界")
+                }
+                for i := 0; i < length; i++ {
+                    out = append(out, out[src+i])
+                }
+            }
+        }
+    }
+    return out, nil
+}
+
+func vbaCopyTokenBits(difference int) uint {
+    bits := uint(4)
+    for (1 << bits) < difference {
+        bits++
+    }
+    return bits
+}
+
+// vbaCompress 按 MS-OVBA 压缩，每块 4096 字节
+func vbaCompress(data []byte) []byte {
+    out := []byte{0x01}
+    for start := 0; start < len(data); start += 4096 {
+        end := start + 4096
+        if end > len(data) {
+            end = len(data)
+        }
+        chunk := vbaCompressChunk(data[start:end])
+        if len(chunk) > 4096 {
+            // 压缩后超过块容量时只能用未压缩块。未压缩块固定为 4096 字节，
+            // 末尾不足一块且无法压缩(超过 3640 字节的随机数据)时按规范补 0
+            raw := make([]byte, 4096)
+            copy(raw, data[start:end])
+            out = binary.LittleEndian.AppendUint16(out, 0x3000|4095)
+            out = append(out, raw...)
+            continue
+        }
+        out = binary.LittleEndian.AppendUint16(out, 0xB000|uint16(len(chunk)+2-3))
+        out = append(out, chunk...)
+    }
+    return out
+}
+
+func vbaCompressChunk(chunk []byte) []byte {
+    var out []byte
+    pos := 0
+    for pos < len(chunk) {
+        flagPos := len(out)
+        out = append(out, 0)
+        for bit := 0; bit < 8 && pos < len(chunk); bit++ {
+            bitCount := vbaCopyTokenBits(pos)
+            maxLength := int(0xFFFF>>bitCount) + 3
+            maxOffset := 1 << (16 - bitCount)
+            bestLen, bestOff := 0, 0
+            for cand := pos - 1; cand >= 0 && pos-cand <= maxOffset; cand-- {
+                n := 0
+                for pos+n < len(chunk) && n < maxLength && chunk[cand+n] == chunk[pos+n] {
+                    n++
+                }
+                if n > bestLen {
+                    bestLen, bestOff = n, pos-cand
+                }
+            }
+            if bestLen >= 3 {
+                token := uint16(bestOff-1)<<(16-bitCount) | uint16(bestLen-3)
+                out = binary.LittleEndian.AppendUint16(out, token)
+                out[flagPos] |= 1 << bit
+                pos += bestLen
+            } else {
+                out = append(out, chunk[pos])
+                pos++
+            }
+        }
+    }
+    return out
+}
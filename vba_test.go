@@ -0,0 +1,67 @@
+package main
+
+import (
+    "bytes"
+    "math/rand"
+    "strings"
+    "testing"
+)
+
+func TestVbaCompressRoundTrip(t *testing.T) {
+    source := []byte(strings.Repeat("Attribute VB_Name = \"Module1\"\r\nSub Test()\r\nEnd Sub\r\n", 300))
+    noise := make([]byte, 9000)
+    rand.New(rand.NewSource(1)).Read(noise)
+
+    cases := []struct {
+        name string
+        data []byte
+    }{
+        {"空", nil},
+        {"短文本", source[:10]},
+        {"不足一块", source[:3700]},
+        {"差一字节", source[:4095]},
+        {"整块", source[:4096]},
+        {"多块", source[:9000]},
+        {"随机整块", noise[:4096]},
+        {"随机短尾", noise[:4096+900]},
+        {"随机可压缩短尾", append(append([]byte(nil), noise[:4096]...), source[:4000]...)},
+    }
+    for _, c := range cases {
+        out, err := vbaDecompress(vbaCompress(c.data))
+        if err != nil {
+            t.Errorf("%s: %v", c.name, err)
+            continue
+        }
+        if !bytes.Equal(out, c.data) {
+            t.Errorf("%s: 解压后 %d 字节，原始 %d 字节", c.name, len(out), len(c.data))
+        }
+    }
+}
+
+// 无法压缩的末尾短块只能存为未压缩块，按 MS-OVBA 补 0 到 4096 字节
+func TestVbaCompressIncompressibleTail(t *testing.T) {
+    noise := make([]byte, 3900)
+    rand.New(rand.NewSource(2)).Read(noise)
+    out, err := vbaDecompress(vbaCompress(noise))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(out) != 4096 || !bytes.Equal(out[:len(noise)], noise) || bytes.Count(out[len(noise):], []byte{0}) != 4096-len(noise) {
+        t.Errorf("未压缩短块应原样保留并补 0，得到 %d 字节", len(out))
+    }
+}
+
+func TestParseVbaDirIgnoresPadding(t *testing.T) {
+    dir := buildVbaDir([]vbaRecord{
+        {ID: vbaProjectCodePage, Data: []byte{0xE4, 0x04}},
+        {ID: vbaDirTerminator},
+    })
+    padded := append(append([]byte(nil), dir...), make([]byte, 4096-len(dir))...)
+    records, err := parseVbaDir(padded)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := buildVbaDir(records); !bytes.Equal(got, dir) {
+        t.Errorf("重建的 dir 流为 %d 字节，应为 %d 字节", len(got), len(dir))
+    }
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+var (
+    sheetViewRe  = regexp.MustCompile(`<sheetView\b[^>]*>`)
+    wordViewRe   = regexp.MustCompile(`<w:view\b[^>]*/>`)
+    wordZoomRe   = regexp.MustCompile(`<w:zoom\b[^>]*>`)
+    viewPrRe     = regexp.MustCompile(`<p:viewPr\b[^>]*>`)
+    viewScaleRe  = regexp.MustCompile(`<a:s[xy]\b[^>]*>`)
+    viewOriginRe = regexp.MustCompile(`<p:origin\b[^>]*>`)
+)
+
+// 工作簿窗口位置、工作表滚动位置和缩放比例，删除后均为默认值
+var (
+    workbookViewAttrs = []string{"xWindow", "yWindow", "windowWidth", "windowHeight", "tabRatio", "activeTab", "firstSheet"}
+    sheetViewAttrs    = []string{"topLeftCell", "zoomScale", "zoomScaleNormal", "zoomScalePageLayoutView", "zoomScaleSheetLayoutView", "view", "tabSelected"}
+)
+
+// normalizeViews 把视图状态恢复为默认: 第一个可见工作表的 A1、第一张幻灯片、100% 缩放
+func normalizeViews(dir string) error {
+    main := mainDocumentPart(dir)
+    switch {
+    case strings.HasPrefix(main, "word/"):
+        return normalizeWordView(dir)
+    case strings.HasPrefix(main, "xl/"):
+        return normalizeWorkbookView(dir, main)
+    case strings.HasPrefix(main, "ppt/"):
+        return normalizePresentationView(dir)
+    }
+    return nil
+}
+
+func normalizeWordView(dir string) error {
+    data, err := readPart(dir, "word/settings.xml")
+    if err != nil {
+        return nil
+    }
+    text := wordViewRe.ReplaceAllString(string(data), "")
+    text = wordZoomRe.ReplaceAllStringFunc(text, func(tag string) string {
+        return setXMLAttr(removeXMLAttr(tag, "w:val"), "w:percent", "100")
+    })
+    logPrintf("重置视图和缩放: word/settings.xml")
+    return writePart(dir, "word/settings.xml", []byte(text))
+}
+
+func normalizeWorkbookView(dir, wb string) error {
+    sheets := workbookSheets(dir, wb)
+    active := 0
+    for i, s := range sheets {
+        if s.State == "" || s.State == "visible" {
+            active = i
+            break
+        }
+    }
+
+    data, err := readPart(dir, wb)
+    if err != nil {
+        return err
+    }
+    text := bookViewTagRe.ReplaceAllStringFunc(string(data), func(tag string) string {
+        for _, a := range workbookViewAttrs {
+            tag = removeXMLAttr(tag, a)
+        }
+        if active > 0 {
+            tag = setXMLAttr(tag, "activeTab", fmt.Sprint(active))
+        }
+        return tag
+    })
+    if err := writePart(dir, wb, []byte(text)); err != nil {
+        return err
+    }
+
+    for i, s := range sheets {
+        data, err := readPart(dir, s.Part)
+        if err != nil {
+            continue
+        }
+        text := removeSpans(string(data), xmlElementSpans(string(data), "selection"))
+        text = sheetViewRe.ReplaceAllStringFunc(text, func(tag string) string {
+            for _, a := range sheetViewAttrs {
+                tag = removeXMLAttr(tag, a)
+            }
+            if i == active {
+                tag = setXMLAttr(tag, "tabSelected", "1")
+            }
+            return tag
+        })
+        if err := writePart(dir, s.Part, []byte(text)); err != nil {
+            return err
+        }
+    }
+    if len(sheets) > 0 {
+        logPrintf("重置工作簿视图: 活动工作表 %s", sheets[active].Name)
+    }
+    return nil
+}
+
+func normalizePresentationView(dir string) error {
+    if data, err := readPart(dir, "ppt/viewProps.xml"); err == nil {
+        text := viewPrRe.ReplaceAllStringFunc(string(data), func(tag string) string {
+            return removeXMLAttr(tag, "lastView")
+        })
+        text = viewScaleRe.ReplaceAllStringFunc(text, func(tag string) string {
+            return setXMLAttr(setXMLAttr(tag, "n", "100"), "d", "100")
+        })
+        text = viewOriginRe.ReplaceAllStringFunc(text, func(tag string) string {
+            return setXMLAttr(setXMLAttr(tag, "x", "0"), "y", "0")
+        })
+        if err := writePart(dir, "ppt/viewProps.xml", []byte(text)); err != nil {
+            return err
+        }
+        logPrintf("重置视图和缩放: ppt/viewProps.xml")
+    }
+
+    // 最近使用的颜色列表
+    if data, err := readPart(dir, "ppt/presProps.xml"); err == nil {
+        text := removeSpans(string(data), xmlElementSpans(string(data), "p:clrMru"))
+        if err := writePart(dir, "ppt/presProps.xml", []byte(text)); err != nil {
+            return err
+        }
+    }
+    return nil
+}